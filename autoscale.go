@@ -0,0 +1,263 @@
+package tqwp
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+const defaultAutoscaleInterval = time.Second
+
+// ScalingReason describes why the autoscaler changed the number of workers.
+type ScalingReason string
+
+const (
+	// ScaleUpQueueDepth means the queue held more tasks than
+	// QueueDepthThreshold, or held tasks while the pool had no workers.
+	ScaleUpQueueDepth ScalingReason = "queue depth"
+
+	// ScaleUpWaitTime means tasks were estimated to wait in the queue
+	// longer than WaitTimeThreshold.
+	ScaleUpWaitTime ScalingReason = "wait time"
+
+	// ScaleDownIdle means the queue was empty and some workers were idle
+	// for IdleTimeout.
+	ScaleDownIdle ScalingReason = "idle"
+)
+
+// ScalingEvent records a scaling decision of the autoscaler.
+type ScalingEvent struct {
+	// Time is when the decision was made.
+	Time time.Time
+
+	// Reason is why the number of workers changed.
+	Reason ScalingReason
+
+	// From and To are the number of workers before and after scaling.
+	From, To uint
+
+	// QueueDepth, WaitTime and BusyWorkers are the measurements the
+	// decision was based on.
+	QueueDepth  int
+	WaitTime    time.Duration
+	BusyWorkers uint
+}
+
+// String returns a human-readable description of the event.
+func (e ScalingEvent) String() string {
+	return fmt.Sprintf(
+		"scaled from %d to %d workers (%s): queue depth %d, wait time %v, %d busy workers",
+		e.From, e.To, e.Reason, e.QueueDepth, e.WaitTime, e.BusyWorkers,
+	)
+}
+
+// AutoscaleConfig holds configuration parameters for the autoscaler of a
+// WorkerPool. The autoscaler periodically samples the queue and the workers,
+// adds workers when tasks pile up, and removes workers that stay idle.
+type AutoscaleConfig struct {
+	// MinWorkers and MaxWorkers bound the number of workers. MinWorkers
+	// defaults to one, so the pool never scales down to no workers at all.
+	// MaxWorkers defaults to the larger of MinWorkers and
+	// WorkerPoolConfig.NumOfWorkers, and a MaxWorkers below MinWorkers is
+	// treated as MinWorkers.
+	MinWorkers uint
+	MaxWorkers uint
+
+	// Interval specifies how often the autoscaler samples the pool.
+	// It defaults to one second.
+	Interval time.Duration
+
+	// QueueDepthThreshold specifies the number of waiting tasks above which
+	// workers are added. Zero disables scaling on queue depth.
+	QueueDepthThreshold int
+
+	// WaitTimeThreshold specifies the estimated queue wait time above which
+	// workers are added. The wait time is estimated from the queue depth and
+	// the rate at which workers take tasks from the queue. Zero disables
+	// scaling on wait time.
+	WaitTimeThreshold time.Duration
+
+	// IdleTimeout specifies how long the queue must stay empty with idle
+	// workers before workers are removed. Zero disables scaling down.
+	IdleTimeout time.Duration
+
+	// ScaleUpStep and ScaleDownStep specify the number of workers added or
+	// removed at once. They default to one.
+	ScaleUpStep   uint
+	ScaleDownStep uint
+
+	// ScaleUpCooldown specifies the minimum time between two scale-ups.
+	ScaleUpCooldown time.Duration
+
+	// ScaleDownCooldown specifies the minimum time between any scaling and
+	// a following scale-down, so a burst that just caused a scale-up does
+	// not immediately scale the pool down again.
+	ScaleDownCooldown time.Duration
+
+	// OnScale is an optional hook called with every scaling decision, for
+	// example to keep an audit trail. It is called from the autoscaler's
+	// goroutine and should return quickly.
+	OnScale func(ScalingEvent)
+}
+
+// autoscaler adjusts the number of workers of a pool.
+type autoscaler struct {
+	pool *WorkerPool
+	cfg  AutoscaleConfig
+
+	lastPopped   uint64
+	lastSample   time.Time
+	lastProgress time.Time
+	lastScaleUp  time.Time
+	lastScale    time.Time
+	idleSince    time.Time
+}
+
+// newAutoscaler returns an autoscaler for wp, filling in the defaults of cfg
+// for a pool configured with the given number of workers.
+func newAutoscaler(wp *WorkerPool, cfg AutoscaleConfig, workers uint) *autoscaler {
+	if cfg.MinWorkers == 0 {
+		cfg.MinWorkers = 1
+	}
+	if cfg.MaxWorkers == 0 {
+		cfg.MaxWorkers = max(cfg.MinWorkers, workers)
+	}
+	if cfg.MaxWorkers < cfg.MinWorkers {
+		cfg.MaxWorkers = cfg.MinWorkers
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultAutoscaleInterval
+	}
+	if cfg.ScaleUpStep == 0 {
+		cfg.ScaleUpStep = 1
+	}
+	if cfg.ScaleDownStep == 0 {
+		cfg.ScaleDownStep = 1
+	}
+	return &autoscaler{pool: wp, cfg: cfg}
+}
+
+// clamp returns n bounded by the configured minimum and maximum.
+func (a *autoscaler) clamp(n uint) uint {
+	return max(a.cfg.MinWorkers, min(n, a.cfg.MaxWorkers))
+}
+
+// run samples the pool every interval until ctx is done.
+func (a *autoscaler) run(ctx context.Context) {
+	defer a.pool.wg.Done()
+
+	now := time.Now()
+	a.lastSample, a.lastProgress = now, now
+
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			a.sample(now)
+		}
+	}
+}
+
+// sample measures the pool and scales it if needed.
+func (a *autoscaler) sample(now time.Time) {
+	wp := a.pool
+	depth := wp.queue.Len()
+	busy := wp.BusyWorkers()
+	workers := wp.NumWorkers()
+
+	popped := wp.popped.Load()
+	taken := popped - a.lastPopped
+	elapsed := now.Sub(a.lastSample)
+	a.lastPopped, a.lastSample = popped, now
+	if taken > 0 {
+		a.lastProgress = now
+	}
+
+	// By Little's law, a task entering the queue waits about as long as
+	// the workers take to consume the tasks ahead of it.
+	var wait time.Duration
+	switch {
+	case depth == 0:
+	case taken == 0:
+		wait = now.Sub(a.lastProgress)
+	default:
+		wait = time.Duration(float64(depth) * float64(elapsed) / float64(taken))
+	}
+
+	event := ScalingEvent{
+		Time:        now,
+		From:        workers,
+		QueueDepth:  depth,
+		WaitTime:    wait,
+		BusyWorkers: busy,
+	}
+
+	switch {
+	case depth > 0 && workers == 0:
+		// Workers removed with SetWorkers would strand the tasks below
+		// the thresholds.
+		event.Reason = ScaleUpQueueDepth
+	case a.cfg.QueueDepthThreshold > 0 && depth > a.cfg.QueueDepthThreshold:
+		event.Reason = ScaleUpQueueDepth
+	case a.cfg.WaitTimeThreshold > 0 && wait > a.cfg.WaitTimeThreshold:
+		event.Reason = ScaleUpWaitTime
+	}
+
+	if event.Reason != "" {
+		a.idleSince = time.Time{}
+		if workers >= a.cfg.MaxWorkers || now.Sub(a.lastScaleUp) < a.cfg.ScaleUpCooldown {
+			return
+		}
+		event.To = a.clamp(workers + a.cfg.ScaleUpStep)
+		a.lastScaleUp = now
+		a.scale(event)
+		return
+	}
+
+	if depth > 0 || busy >= workers {
+		a.idleSince = time.Time{}
+		return
+	}
+	if a.idleSince.IsZero() {
+		a.idleSince = now
+	}
+	if a.cfg.IdleTimeout <= 0 || now.Sub(a.idleSince) < a.cfg.IdleTimeout {
+		return
+	}
+	if workers <= a.cfg.MinWorkers || now.Sub(a.lastScale) < a.cfg.ScaleDownCooldown {
+		return
+	}
+
+	event.Reason = ScaleDownIdle
+	event.To = a.clamp(workers - min(workers, a.cfg.ScaleDownStep))
+	a.idleSince = now
+	a.scale(event)
+}
+
+// scale applies the decision recorded in event and reports it.
+func (a *autoscaler) scale(event ScalingEvent) {
+	a.lastScale = event.Time
+	a.pool.SetWorkers(event.To)
+
+	a.pool.logger.Log(LevelInfo, "Autoscaler scaled workers",
+		Field{Key: "reason", Value: string(event.Reason)},
+		Field{Key: "from", Value: event.From},
+		Field{Key: "to", Value: event.To},
+		Field{Key: "queue_depth", Value: event.QueueDepth},
+		Field{Key: "wait_time", Value: event.WaitTime},
+		Field{Key: "busy_workers", Value: event.BusyWorkers},
+	)
+	if a.cfg.OnScale != nil {
+		a.cfg.OnScale(event)
+	}
+}
+
+// BusyWorkers returns the number of workers currently processing a task.
+func (wp *WorkerPool) BusyWorkers() uint {
+	return uint(atomic.LoadInt32(&wp.busyWorkers))
+}
@@ -0,0 +1,56 @@
+package tqwp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abdullahnettoor/tqwp"
+)
+
+// TestAutoscaleDefaults checks that an autoscaled pool configured with
+// little more than an idle timeout has workers and terminates.
+func TestAutoscaleDefaults(t *testing.T) {
+	wp := tqwp.New(&tqwp.WorkerPoolConfig{
+		NumOfWorkers: 4,
+		QueueSize:    10,
+		Logger:       tqwp.NopLogger{},
+		Autoscale:    &tqwp.AutoscaleConfig{IdleTimeout: time.Second},
+	})
+	if n := wp.NumWorkers(); n != 4 {
+		t.Fatalf("NumWorkers = %d, want 4", n)
+	}
+
+	wp.Start()
+	for i := 0; i < 10; i++ {
+		wp.EnqueueTask(&flakyTask{})
+	}
+	stopWithin(t, wp, time.Minute)
+
+	if wp.TaskFailure != 10 {
+		t.Fatalf("TaskFailure = %d, want 10", wp.TaskFailure)
+	}
+}
+
+// TestAutoscaleWithoutWorkers checks that the autoscaler adds a worker to
+// a pool left without workers once tasks are queued.
+func TestAutoscaleWithoutWorkers(t *testing.T) {
+	wp := tqwp.New(&tqwp.WorkerPoolConfig{
+		NumOfWorkers: 1,
+		QueueSize:    10,
+		Logger:       tqwp.NopLogger{},
+		Autoscale: &tqwp.AutoscaleConfig{
+			MaxWorkers:          2,
+			Interval:            10 * time.Millisecond,
+			QueueDepthThreshold: 100,
+		},
+	})
+
+	wp.Start()
+	wp.SetWorkers(0)
+	wp.EnqueueTask(&flakyTask{})
+	stopWithin(t, wp, 10*time.Second)
+
+	if wp.TaskFailure != 1 {
+		t.Fatalf("TaskFailure = %d, want 1", wp.TaskFailure)
+	}
+}
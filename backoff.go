@@ -0,0 +1,120 @@
+package tqwp
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// BackoffStrategy decides how long a failed task waits before it is retried.
+// Workers never sleep for the delay themselves; the task is put back on the
+// queue once the delay has elapsed.
+type BackoffStrategy interface {
+	// Delay returns the wait before the given retry attempt, starting at 1.
+	Delay(attempt uint) time.Duration
+}
+
+// ConstantBackoff waits the same Interval before every retry.
+type ConstantBackoff struct {
+	Interval time.Duration
+}
+
+// Delay implements BackoffStrategy.
+func (b ConstantBackoff) Delay(attempt uint) time.Duration {
+	return b.Interval
+}
+
+// LinearBackoff waits Initial before the first retry and adds Step for
+// every following retry, up to Max when Max is set.
+type LinearBackoff struct {
+	Initial time.Duration
+	Step    time.Duration
+	Max     time.Duration
+}
+
+// Delay implements BackoffStrategy.
+func (b LinearBackoff) Delay(attempt uint) time.Duration {
+	if attempt == 0 {
+		attempt = 1
+	}
+	return capDelay(floatDelay(float64(b.Initial)+float64(attempt-1)*float64(b.Step)), b.Max)
+}
+
+// ExponentialBackoff waits Initial before the first retry and multiplies the
+// delay by Multiplier for every following retry, up to Max when Max is set.
+// A Multiplier below 1 defaults to 2.
+type ExponentialBackoff struct {
+	Initial    time.Duration
+	Multiplier float64
+	Max        time.Duration
+}
+
+// Delay implements BackoffStrategy.
+func (b ExponentialBackoff) Delay(attempt uint) time.Duration {
+	if attempt == 0 {
+		attempt = 1
+	}
+	mult := b.Multiplier
+	if mult < 1 {
+		mult = 2
+	}
+
+	return capDelay(floatDelay(float64(b.Initial)*math.Pow(mult, float64(attempt-1))), b.Max)
+}
+
+// DecorrelatedJitterBackoff implements the "decorrelated jitter" algorithm,
+// where each delay is picked at random between Base and three times the
+// previous delay, capped at Max. It spreads out retries of many tasks that
+// failed at the same time, e.g. because a remote endpoint went down.
+//
+// The strategy is stateless: the previous delays of a task are re-drawn for
+// every call, which yields the same distribution of delays per attempt.
+// A Max below Base is treated as Base.
+type DecorrelatedJitterBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Delay implements BackoffStrategy.
+func (b DecorrelatedJitterBackoff) Delay(attempt uint) time.Duration {
+	if b.Base <= 0 {
+		return 0
+	}
+	if b.Max > 0 && b.Max < b.Base {
+		b.Max = b.Base
+	}
+
+	d := b.Base
+	for i := uint(0); i < attempt; i++ {
+		upper := time.Duration(math.MaxInt64)
+		if d < upper/3 {
+			upper = d * 3
+		}
+		d = capDelay(b.Base+time.Duration(rand.Int63n(int64(upper-b.Base)+1)), b.Max)
+	}
+	return d
+}
+
+// floatDelay converts d to a Duration, saturating at the largest Duration
+// instead of overflowing. Negative delays, and the NaN of a zero delay
+// multiplied by an infinite factor, are returned as zero.
+func floatDelay(d float64) time.Duration {
+	switch {
+	case d >= math.MaxInt64:
+		return math.MaxInt64
+	case d < 0 || math.IsNaN(d):
+		return 0
+	}
+	return time.Duration(d)
+}
+
+// capDelay limits d to max, treating a max of zero as no limit.
+func capDelay(d, max time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	if max > 0 && d > max {
+		return max
+	}
+	return d
+}
@@ -0,0 +1,67 @@
+package tqwp_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/abdullahnettoor/tqwp"
+)
+
+func TestBackoffDelays(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy tqwp.BackoffStrategy
+		attempt  uint
+		want     time.Duration
+	}{
+		{"Constant", tqwp.ConstantBackoff{Interval: time.Second}, 7, time.Second},
+
+		{"LinearFirst", tqwp.LinearBackoff{Initial: time.Second, Step: time.Second}, 1, time.Second},
+		{"LinearZeroAttempt", tqwp.LinearBackoff{Initial: time.Second, Step: time.Second}, 0, time.Second},
+		{"LinearThird", tqwp.LinearBackoff{Initial: time.Second, Step: 2 * time.Second}, 3, 5 * time.Second},
+		{"LinearCapped", tqwp.LinearBackoff{Initial: time.Second, Step: time.Second, Max: 3 * time.Second}, 10, 3 * time.Second},
+		{"LinearOverflowCapped", tqwp.LinearBackoff{Initial: time.Second, Step: time.Hour, Max: time.Hour}, 1 << 40, time.Hour},
+		{"LinearOverflowUncapped", tqwp.LinearBackoff{Step: time.Hour}, 1 << 40, math.MaxInt64},
+		{"LinearNegative", tqwp.LinearBackoff{Initial: time.Second, Step: -time.Second}, 5, 0},
+
+		{"ExponentialFirst", tqwp.ExponentialBackoff{Initial: time.Second}, 1, time.Second},
+		{"ExponentialDefaultMultiplier", tqwp.ExponentialBackoff{Initial: time.Second}, 4, 8 * time.Second},
+		{"ExponentialMultiplier", tqwp.ExponentialBackoff{Initial: time.Second, Multiplier: 3}, 3, 9 * time.Second},
+		{"ExponentialCapped", tqwp.ExponentialBackoff{Initial: time.Second, Max: time.Hour}, 30, time.Hour},
+		{"ExponentialOverflow35", tqwp.ExponentialBackoff{Initial: time.Second, Max: time.Hour}, 35, time.Hour},
+		{"ExponentialOverflow70", tqwp.ExponentialBackoff{Initial: time.Second, Max: time.Hour}, 70, time.Hour},
+		{"ExponentialOverflowInfinite", tqwp.ExponentialBackoff{Initial: time.Second, Max: time.Hour}, 5000, time.Hour},
+		{"ExponentialOverflowUncapped", tqwp.ExponentialBackoff{Initial: time.Second}, 70, math.MaxInt64},
+		{"ExponentialZeroInitial", tqwp.ExponentialBackoff{Max: time.Hour}, 5000, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.strategy.Delay(tt.attempt); got != tt.want {
+				t.Fatalf("Delay(%d) = %v, want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecorrelatedJitterBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		b        tqwp.DecorrelatedJitterBackoff
+		min, max time.Duration
+	}{
+		{"Bounded", tqwp.DecorrelatedJitterBackoff{Base: 100 * time.Millisecond, Max: 5 * time.Second}, 100 * time.Millisecond, 5 * time.Second},
+		{"MaxBelowBase", tqwp.DecorrelatedJitterBackoff{Base: time.Second, Max: 200 * time.Millisecond}, time.Second, time.Second},
+		{"Uncapped", tqwp.DecorrelatedJitterBackoff{Base: time.Second}, time.Second, math.MaxInt64},
+		{"ZeroBase", tqwp.DecorrelatedJitterBackoff{Max: time.Second}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for attempt := uint(1); attempt <= 100; attempt++ {
+				if d := tt.b.Delay(attempt); d < tt.min || d > tt.max {
+					t.Fatalf("Delay(%d) = %v, want within [%v, %v]", attempt, d, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
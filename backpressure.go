@@ -0,0 +1,273 @@
+package tqwp
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+var (
+	// ErrQueueFull is returned when a task cannot be enqueued without
+	// waiting for room in the queue.
+	ErrQueueFull = errors.New("queue full")
+
+	// ErrTaskDropped is the final error of tasks discarded by the
+	// OverflowDropNewest and OverflowDropOldest policies.
+	ErrTaskDropped = errors.New("task dropped")
+)
+
+// OverflowPolicy specifies what happens to a task enqueued while the queue
+// is full.
+type OverflowPolicy uint8
+
+const (
+	// OverflowBlock waits for room in the queue. It is the default.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowReject refuses the task with ErrQueueFull.
+	OverflowReject
+
+	// OverflowDropNewest accepts the task but discards it immediately.
+	OverflowDropNewest
+
+	// OverflowDropOldest discards the oldest task in the queue to make room
+	// for the new one. Queues that do not implement Evicter discard the new
+	// task instead.
+	OverflowDropOldest
+
+	// OverflowSpill keeps the task in an overflow buffer held by the pool,
+	// which feeds the queue in FIFO order as room frees up. Spilled tasks
+	// are not seen by the queue until then, so they are not persisted by
+	// a DurableQueue. Once the buffer holds SpillSize tasks, new tasks are
+	// rejected with ErrQueueFull.
+	OverflowSpill
+)
+
+// TryPusher is an optional interface for queue backends that can add a
+// task without blocking. The overflow policies rely on it; for backends
+// that do not implement it, a full queue is detected by calling Push with
+// an already cancelled context.
+type TryPusher interface {
+	// TryPush adds a task, returning ErrQueueFull if the queue is full.
+	TryPush(task Task) error
+}
+
+// Evicter is an optional interface for queue backends that can discard
+// their oldest task, as required by OverflowDropOldest.
+type Evicter interface {
+	// EvictOldest removes and returns the task that was pushed first.
+	// It returns false if the queue is empty.
+	EvictOldest() (Task, bool)
+}
+
+// EnqueueContext adds a task to the queue, applying the pool's overflow
+// policy when the queue is full. With OverflowBlock it waits for room
+// until ctx is done.
+//
+// A task that is rejected or dropped is completed with ErrQueueFull or
+// ErrTaskDropped, so futures and typed results of such tasks resolve.
+// Dropped tasks are counted as accepted and EnqueueContext returns nil.
+// A task refused for any other reason, such as ctx being done or the queue
+// being closed, is completed with the returned error.
+func (wp *WorkerPool) EnqueueContext(ctx context.Context, task Task) error {
+	return wp.enqueueTaskContext(ctx, task)
+}
+
+// TryEnqueue is like EnqueueContext, but never waits for room in the queue.
+// With OverflowBlock it rejects the task with ErrQueueFull.
+func (wp *WorkerPool) TryEnqueue(task Task) error {
+	policy := wp.overflow
+	if policy == OverflowBlock {
+		policy = OverflowReject
+	}
+	return wp.enqueuePolicy(context.Background(), task, policy)
+}
+
+// enqueueTaskContext adds task to the queue, honouring the overflow policy.
+// The span of the task is started as a child of the span carried by ctx.
+func (wp *WorkerPool) enqueueTaskContext(ctx context.Context, task Task) error {
+	return wp.enqueuePolicy(ctx, task, wp.overflow)
+}
+
+// enqueuePolicy is like enqueueTaskContext, but applies policy instead of
+// the pool's overflow policy.
+func (wp *WorkerPool) enqueuePolicy(ctx context.Context, task Task, policy OverflowPolicy) error {
+	wp.startTaskSpan(ctx, task)
+	wp.emitTask(EventEnqueue, task, nil)
+	if policy != OverflowBlock {
+		return wp.enqueueOverflow(task, policy)
+	}
+
+	wp.taskWg.Add(1)
+	if err := wp.queue.Push(ctx, task); err != nil {
+		wp.refuseTask(task, err)
+		return err
+	}
+	wp.taskEnqueued()
+	return nil
+}
+
+// enqueueOverflow adds task to the queue without blocking, applying policy
+// if the queue is full.
+func (wp *WorkerPool) enqueueOverflow(task Task, policy OverflowPolicy) error {
+	if policy == OverflowSpill {
+		return wp.spillTask(task)
+	}
+
+	wp.taskWg.Add(1)
+	for {
+		err := wp.tryPush(task)
+		if !errors.Is(err, ErrQueueFull) {
+			if err != nil {
+				wp.refuseTask(task, err)
+				return err
+			}
+			wp.taskEnqueued()
+			return nil
+		}
+
+		switch policy {
+		case OverflowDropNewest:
+			wp.dropTask(task)
+			return nil
+		case OverflowDropOldest:
+			evicter, ok := wp.queue.(Evicter)
+			if !ok {
+				wp.dropTask(task)
+				return nil
+			}
+			// The queue may have drained since the push failed, in which
+			// case nothing is evicted and the push is simply retried.
+			if oldest, ok := evicter.EvictOldest(); ok {
+				wp.dropTask(oldest)
+			}
+		default:
+			wp.rejectTask(task)
+			return ErrQueueFull
+		}
+	}
+}
+
+// tryPush adds task to the queue without blocking.
+func (wp *WorkerPool) tryPush(task Task) error {
+	if tp, ok := wp.queue.(TryPusher); ok {
+		return tp.TryPush(task)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := wp.queue.Push(ctx, task)
+	if errors.Is(err, context.Canceled) {
+		return ErrQueueFull
+	}
+	return err
+}
+
+// refuseTask accounts for a task the queue refused with err, for another
+// reason than being full.
+func (wp *WorkerPool) refuseTask(task Task, err error) {
+	wp.emitTask(EventDrop, task, err)
+	wp.endTaskSpan(task, err)
+	completeTask(task, err)
+	wp.taskWg.Done()
+}
+
+// rejectTask accounts for a task refused because the queue is full.
+func (wp *WorkerPool) rejectTask(task Task) {
+	atomic.AddUint32(&wp.TaskRejected, 1)
+	wp.emitTask(EventDrop, task, ErrQueueFull)
+	wp.endTaskSpan(task, ErrQueueFull)
+	completeTask(task, ErrQueueFull)
+	wp.taskWg.Done()
+}
+
+// dropTask accounts for a task discarded by the overflow policy.
+func (wp *WorkerPool) dropTask(task Task) {
+	atomic.AddUint32(&wp.TaskDropped, 1)
+	wp.emitTask(EventDrop, task, ErrTaskDropped)
+	wp.endTaskSpan(task, ErrTaskDropped)
+	completeTask(task, ErrTaskDropped)
+	wp.taskWg.Done()
+}
+
+// spillTask adds task to the queue, or to the overflow buffer if the queue
+// is full or other tasks are already waiting in the buffer.
+//
+// Workers move spilled tasks to the queue each time they pop a task, and
+// the pool's feeder pushes them while waiting for room; see retryLane.
+func (wp *WorkerPool) spillTask(task Task) error {
+	wp.taskWg.Add(1)
+	err := wp.bufferTask(task)
+	switch {
+	case errors.Is(err, ErrQueueFull):
+		wp.rejectTask(task)
+	case err != nil:
+		wp.refuseTask(task, err)
+	}
+	return err
+}
+
+// bufferTask is the part of spillTask run with wp.spillMu held. It returns
+// ErrQueueFull if the buffer is full too, and leaves refused tasks to the
+// caller, so listeners are never called with the lock held.
+func (wp *WorkerPool) bufferTask(task Task) error {
+	wp.spillMu.Lock()
+	defer wp.spillMu.Unlock()
+
+	if len(wp.spill) == 0 {
+		err := wp.tryPush(task)
+		if !errors.Is(err, ErrQueueFull) {
+			if err == nil {
+				wp.taskEnqueued()
+			}
+			return err
+		}
+	}
+
+	if wp.spillSize > 0 && len(wp.spill) >= wp.spillSize {
+		return ErrQueueFull
+	}
+	wp.spill = append(wp.spill, task)
+	atomic.AddUint32(&wp.TaskSpilled, 1)
+	wp.metrics.TaskEnqueued()
+	wp.drainSpillLocked()
+	if len(wp.spill) > 0 {
+		wp.wakeFeeder()
+	}
+	return nil
+}
+
+// drainSpill moves spilled tasks to the queue while it has room.
+func (wp *WorkerPool) drainSpill() {
+	if wp.overflow != OverflowSpill {
+		return
+	}
+	wp.spillMu.Lock()
+	defer wp.spillMu.Unlock()
+	wp.drainSpillLocked()
+}
+
+// drainSpillLocked is like drainSpill, but must be called with wp.spillMu held.
+func (wp *WorkerPool) drainSpillLocked() {
+	for len(wp.spill) > 0 {
+		if err := wp.tryPush(wp.spill[0]); err != nil {
+			return
+		}
+		wp.spill[0] = nil
+		wp.spill = wp.spill[1:]
+	}
+}
+
+// discardSpill abandons the tasks left in the overflow buffer when the pool
+// is stopped.
+func (wp *WorkerPool) discardSpill(err error) {
+	wp.spillMu.Lock()
+	spill := wp.spill
+	wp.spill = nil
+	wp.spillMu.Unlock()
+
+	for _, task := range spill {
+		wp.cancelTask(task, err)
+		wp.taskWg.Done()
+	}
+}
@@ -0,0 +1,550 @@
+package tqwp
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Codec encodes values into bytes and decodes them back. Codecs are used
+// with a Registry to serialize tasks together with their retry state.
+type Codec interface {
+	// Marshal returns the encoding of v.
+	Marshal(v any) ([]byte, error)
+
+	// Unmarshal decodes data into the value pointed to by v.
+	Unmarshal(data []byte, v any) error
+}
+
+// Built-in codecs.
+var (
+	// JSONCodec encodes values with encoding/json.
+	JSONCodec Codec = jsonCodec{}
+
+	// GobCodec encodes values with encoding/gob. Struct fields whose type
+	// has no exported fields, such as an embedded TaskModel, are left out
+	// instead of failing the encoding.
+	GobCodec Codec = gobCodec{}
+
+	// BinaryCodec encodes values in a compact, schema-less binary format.
+	// The exported fields of a struct are written in declaration order
+	// without their names, so the encoding is only valid for the same
+	// version of the type. Map entries are sorted by key, so equal values
+	// always encode to the same bytes. Types implementing
+	// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler, such as
+	// time.Time, encode themselves. Nil and empty slices and maps both
+	// decode as empty ones. Interfaces, channels and functions are not
+	// supported.
+	BinaryCodec Codec = binaryCodec{}
+)
+
+// Envelope holds a serialized task together with its registered type name,
+// retry state and span context.
+type Envelope struct {
+	Type    string
+	Retries uint
+	Payload []byte
+	Trace   SpanContext
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Struct {
+		if shadow := gobShadowOf(rv.Type()); shadow.typ != nil {
+			sv := reflect.New(shadow.typ).Elem()
+			for i, idx := range shadow.fields {
+				sv.Field(i).Set(rv.Field(idx))
+			}
+			v = sv.Interface()
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() == reflect.Struct {
+		if shadow := gobShadowOf(rv.Elem().Type()); shadow.typ != nil {
+			sp := reflect.New(shadow.typ)
+			if err := gob.NewDecoder(bytes.NewReader(data)).Decode(sp.Interface()); err != nil {
+				return err
+			}
+			for i, idx := range shadow.fields {
+				rv.Elem().Field(idx).Set(sp.Elem().Field(i))
+			}
+			return nil
+		}
+	}
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+var (
+	gobEncoderType    = reflect.TypeOf((*gob.GobEncoder)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	// gobShadows caches the result of gobShadowOf by struct type.
+	gobShadows sync.Map
+)
+
+// gobShadow is a struct type holding the fields of another struct type
+// that gob can encode.
+type gobShadow struct {
+	// typ is nil if every exported field of the original type can be
+	// encoded by gob.
+	typ reflect.Type
+
+	// fields holds the index in the original type of each field of typ.
+	fields []int
+}
+
+// gobShadowOf returns the gobShadow of struct type t. gob refuses fields
+// whose struct type has no exported fields, which is the case of an
+// embedded TaskModel; the shadow leaves them out. The state of TaskModel is
+// carried by the Envelope instead.
+func gobShadowOf(t reflect.Type) *gobShadow {
+	if s, ok := gobShadows.Load(t); ok {
+		return s.(*gobShadow)
+	}
+
+	shadow := &gobShadow{}
+	if !gobEncodesItself(t) {
+		var fields []reflect.StructField
+		skipped := false
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if !gobCanSend(f.Type) {
+				skipped = true
+				continue
+			}
+			fields = append(fields, reflect.StructField{Name: f.Name, Type: f.Type, Tag: f.Tag})
+			shadow.fields = append(shadow.fields, i)
+		}
+		if skipped {
+			shadow.typ = reflect.StructOf(fields)
+		} else {
+			shadow.fields = nil
+		}
+	}
+
+	s, _ := gobShadows.LoadOrStore(t, shadow)
+	return s.(*gobShadow)
+}
+
+// gobCanSend reports whether gob accepts a struct field of type t.
+func gobCanSend(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || gobEncodesItself(t) {
+		return true
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// gobEncodesItself reports whether values of type t, or pointers to them,
+// implement one of the interfaces gob uses to let types encode themselves.
+func gobEncodesItself(t reflect.Type) bool {
+	for _, it := range []reflect.Type{gobEncoderType, binaryMarshalerType, textMarshalerType} {
+		if t.Implements(it) || reflect.PointerTo(t).Implements(it) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+
+	errBinaryShort = errors.New("binary codec: unexpected end of data")
+)
+
+// maxBinaryEmptyLen is the largest length the binary codec decodes for
+// slices and maps whose elements encode to no bytes at all, such as
+// []struct{}, which the remaining data cannot bound.
+const maxBinaryEmptyLen = 1 << 16
+
+type binaryCodec struct{}
+
+func (binaryCodec) Marshal(v any) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, errors.New("binary codec: cannot marshal nil pointer")
+		}
+		rv = rv.Elem()
+	}
+
+	var buf []byte
+	if err := appendBinary(&buf, rv); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func (binaryCodec) Unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("binary codec: cannot unmarshal into %T", v)
+	}
+	rv = rv.Elem()
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+
+	d := &binaryDecoder{data: data}
+	if err := d.decode(rv); err != nil {
+		return err
+	}
+	if len(d.data) > 0 {
+		return fmt.Errorf("binary codec: %d trailing bytes", len(d.data))
+	}
+	return nil
+}
+
+// selfMarshaling reports whether t encodes itself through
+// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler.
+func selfMarshaling(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		return false
+	}
+	ptr := reflect.PointerTo(t)
+	return ptr.Implements(binaryUnmarshalerType) &&
+		(t.Implements(binaryMarshalerType) || ptr.Implements(binaryMarshalerType))
+}
+
+// appendBinary appends the binary encoding of v to buf.
+func appendBinary(buf *[]byte, v reflect.Value) error {
+	if selfMarshaling(v.Type()) {
+		if !v.CanAddr() {
+			c := reflect.New(v.Type()).Elem()
+			c.Set(v)
+			v = c
+		}
+		data, err := v.Addr().Interface().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		*buf = binary.AppendUvarint(*buf, uint64(len(data)))
+		*buf = append(*buf, data...)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			*buf = append(*buf, 1)
+		} else {
+			*buf = append(*buf, 0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		*buf = binary.AppendVarint(*buf, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		*buf = binary.AppendUvarint(*buf, v.Uint())
+	case reflect.Float32:
+		*buf = binary.BigEndian.AppendUint32(*buf, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		*buf = binary.BigEndian.AppendUint64(*buf, math.Float64bits(v.Float()))
+	case reflect.String:
+		*buf = binary.AppendUvarint(*buf, uint64(v.Len()))
+		*buf = append(*buf, v.String()...)
+	case reflect.Slice:
+		*buf = binary.AppendUvarint(*buf, uint64(v.Len()))
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			*buf = append(*buf, v.Bytes()...)
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := appendBinary(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		*buf = binary.AppendUvarint(*buf, uint64(v.Len()))
+
+		type entry struct {
+			key   []byte
+			value reflect.Value
+		}
+		entries := make([]entry, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			var key []byte
+			if err := appendBinary(&key, iter.Key()); err != nil {
+				return err
+			}
+			entries = append(entries, entry{key: key, value: iter.Value()})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return bytes.Compare(entries[i].key, entries[j].key) < 0
+		})
+		for _, e := range entries {
+			*buf = append(*buf, e.key...)
+			if err := appendBinary(buf, e.value); err != nil {
+				return err
+			}
+		}
+	case reflect.Pointer:
+		if v.IsNil() {
+			*buf = append(*buf, 0)
+			return nil
+		}
+		*buf = append(*buf, 1)
+		return appendBinary(buf, v.Elem())
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			if err := appendBinary(buf, v.Field(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("binary codec: unsupported type %v", v.Type())
+	}
+	return nil
+}
+
+// binaryDecoder decodes values encoded by appendBinary.
+type binaryDecoder struct {
+	data []byte
+}
+
+func (d *binaryDecoder) uvarint() (uint64, error) {
+	n, size := binary.Uvarint(d.data)
+	if size <= 0 {
+		return 0, errBinaryShort
+	}
+	d.data = d.data[size:]
+	return n, nil
+}
+
+func (d *binaryDecoder) varint() (int64, error) {
+	n, size := binary.Varint(d.data)
+	if size <= 0 {
+		return 0, errBinaryShort
+	}
+	d.data = d.data[size:]
+	return n, nil
+}
+
+func (d *binaryDecoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)) {
+		return nil, errBinaryShort
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b, nil
+}
+
+// length reads the length prefix of a slice or map whose elements encode
+// to at least min bytes, rejecting lengths that cannot fit in the remaining
+// data.
+func (d *binaryDecoder) length(min int) (int, error) {
+	n, err := d.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if min == 0 {
+		if n > maxBinaryEmptyLen {
+			return 0, fmt.Errorf("binary codec: length %d too large", n)
+		}
+	} else if n > uint64(len(d.data)/min) {
+		return 0, errBinaryShort
+	}
+	return int(n), nil
+}
+
+// minBinarySize returns the minimum number of bytes appendBinary writes for
+// a value of type t.
+func minBinarySize(t reflect.Type) int {
+	if selfMarshaling(t) {
+		return 1
+	}
+	switch t.Kind() {
+	case reflect.Float32:
+		return 4
+	case reflect.Float64:
+		return 8
+	case reflect.Array:
+		return t.Len() * minBinarySize(t.Elem())
+	case reflect.Struct:
+		size := 0
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				size += minBinarySize(t.Field(i).Type)
+			}
+		}
+		return size
+	}
+	return 1
+}
+
+func (d *binaryDecoder) decode(v reflect.Value) error {
+	if selfMarshaling(v.Type()) {
+		n, err := d.uvarint()
+		if err != nil {
+			return err
+		}
+		data, err := d.bytes(n)
+		if err != nil {
+			return err
+		}
+		return v.Addr().Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := d.bytes(1)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b[0] != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := d.varint()
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := d.uvarint()
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32:
+		b, err := d.bytes(4)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(float64(math.Float32frombits(binary.BigEndian.Uint32(b))))
+	case reflect.Float64:
+		b, err := d.bytes(8)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(math.Float64frombits(binary.BigEndian.Uint64(b)))
+	case reflect.String:
+		n, err := d.uvarint()
+		if err != nil {
+			return err
+		}
+		b, err := d.bytes(n)
+		if err != nil {
+			return err
+		}
+		v.SetString(string(b))
+	case reflect.Slice:
+		n, err := d.length(minBinarySize(v.Type().Elem()))
+		if err != nil {
+			return err
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b, err := d.bytes(uint64(n))
+			if err != nil {
+				return err
+			}
+			v.SetBytes(append([]byte(nil), b...))
+			return nil
+		}
+		v.Set(reflect.MakeSlice(v.Type(), n, n))
+		for i := 0; i < n; i++ {
+			if err := d.decode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := d.decode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		t := v.Type()
+		n, err := d.length(minBinarySize(t.Key()) + minBinarySize(t.Elem()))
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.MakeMapWithSize(t, n))
+		for i := 0; i < n; i++ {
+			key := reflect.New(t.Key()).Elem()
+			if err := d.decode(key); err != nil {
+				return err
+			}
+			elem := reflect.New(t.Elem()).Elem()
+			if err := d.decode(elem); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+		}
+	case reflect.Pointer:
+		b, err := d.bytes(1)
+		if err != nil {
+			return err
+		}
+		if b[0] == 0 {
+			v.SetZero()
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(v.Elem())
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			if err := d.decode(v.Field(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("binary codec: unsupported type %v", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package tqwp_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/abdullahnettoor/tqwp"
+)
+
+// codecChild is a nested struct of codecTask.
+type codecChild struct {
+	Name  string
+	Score float32
+}
+
+// codecTask exercises the kinds of values the codecs support.
+type codecTask struct {
+	tqwp.TaskModel
+	Name   string
+	Count  int
+	Ratio  float64
+	Ok     bool
+	Tags   []string
+	Labels map[string]int
+	Data   []byte
+	Pair   [2]uint16
+	At     time.Time
+	Child  *codecChild
+	Limit  *int
+	Trace  string
+}
+
+func (t *codecTask) Process() error { return nil }
+
+func codecRegistry() *tqwp.Registry {
+	reg := tqwp.NewRegistry()
+	reg.Register("codec", func() tqwp.Task { return &codecTask{} })
+	return reg
+}
+
+func newCodecTask() *codecTask {
+	task := &codecTask{
+		Name:   "report",
+		Count:  -42,
+		Ratio:  0.25,
+		Ok:     true,
+		Tags:   []string{"a", "b"},
+		Labels: map[string]int{"x": 1, "y": 2, "z": 3},
+		Data:   []byte{0, 1, 2},
+		Pair:   [2]uint16{7, 65535},
+		At:     time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.UTC),
+		Child:  &codecChild{Name: "child", Score: 1.5},
+		Trace:  "user field",
+	}
+	task.SetRetries(4)
+	task.SetTraceContext(tqwp.SpanContext{
+		TraceID: tqwp.TraceID{1, 2, 3},
+		SpanID:  tqwp.SpanID{4, 5, 6},
+	})
+	return task
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	reg := codecRegistry()
+	for _, c := range walCodecs {
+		t.Run(c.name, func(t *testing.T) {
+			want := newCodecTask()
+			data, err := reg.Marshal(c.codec, want)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			task, err := reg.Unmarshal(c.codec, data)
+			if err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+
+			got := task.(*codecTask)
+			if got.Retries() != want.Retries() || got.TraceContext() != want.TraceContext() {
+				t.Fatalf("decoded %d retries and trace %v, want %d and %v",
+					got.Retries(), got.TraceContext(), want.Retries(), want.TraceContext())
+			}
+			if !got.At.Equal(want.At) {
+				t.Fatalf("decoded time %v, want %v", got.At, want.At)
+			}
+			got.At, want.At = time.Time{}, time.Time{}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("decoded %+v, want %+v", got, want)
+			}
+			if got.Limit != nil {
+				t.Fatalf("nil pointer decoded as %v", *got.Limit)
+			}
+		})
+	}
+}
+
+func TestCodecTruncatedInput(t *testing.T) {
+	reg := codecRegistry()
+	for _, c := range walCodecs {
+		t.Run(c.name, func(t *testing.T) {
+			data, err := reg.Marshal(c.codec, newCodecTask())
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			for n := 0; n < len(data); n++ {
+				if _, err := reg.Unmarshal(c.codec, data[:n]); err == nil {
+					t.Fatalf("Unmarshal of the first %d of %d bytes succeeded", n, len(data))
+				}
+			}
+		})
+	}
+}
+
+func TestCodecCorruptInput(t *testing.T) {
+	reg := codecRegistry()
+	for _, c := range walCodecs {
+		t.Run(c.name, func(t *testing.T) {
+			data, err := reg.Marshal(c.codec, newCodecTask())
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			// Corrupt data may decode to another task, but must never
+			// panic.
+			for i := range data {
+				corrupt := bytes.Clone(data)
+				corrupt[i] ^= 0xff
+				reg.Unmarshal(c.codec, corrupt)
+			}
+		})
+	}
+}
+
+func TestBinaryCodecDeterministicMaps(t *testing.T) {
+	m := make(map[int]string)
+	for i := 0; i < 100; i++ {
+		m[i] = "value"
+	}
+	first, err := tqwp.BinaryCodec.Marshal(m)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		data, _ := tqwp.BinaryCodec.Marshal(m)
+		if !bytes.Equal(data, first) {
+			t.Fatal("equal maps encoded to different bytes")
+		}
+	}
+
+	var got map[int]string
+	if err := tqwp.BinaryCodec.Unmarshal(first, &got); err != nil || !reflect.DeepEqual(got, m) {
+		t.Fatalf("Unmarshal = %v, %v", got, err)
+	}
+}
+
+func TestBinaryCodecLengths(t *testing.T) {
+	type marks struct {
+		Marks []struct{}
+	}
+	want := marks{Marks: make([]struct{}, 3)}
+	data, err := tqwp.BinaryCodec.Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var got marks
+	if err := tqwp.BinaryCodec.Unmarshal(data, &got); err != nil || len(got.Marks) != 3 {
+		t.Fatalf("Unmarshal = %v, %v", got, err)
+	}
+
+	// Lengths the data cannot hold are rejected before allocating.
+	huge := binary.AppendUvarint(nil, 1<<60)
+	if err := tqwp.BinaryCodec.Unmarshal(huge, &got); err == nil {
+		t.Fatal("Unmarshal accepted a huge length of empty elements")
+	}
+	var ints []int64
+	if err := tqwp.BinaryCodec.Unmarshal(huge, &ints); err == nil {
+		t.Fatal("Unmarshal accepted a length longer than the data")
+	}
+	var floats []float64
+	if err := tqwp.BinaryCodec.Unmarshal(append(binary.AppendUvarint(nil, 2), make([]byte, 8)...), &floats); err == nil {
+		t.Fatal("Unmarshal accepted two floats encoded in 8 bytes")
+	}
+}
@@ -0,0 +1,257 @@
+package tqwp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule describes when a recurring task runs.
+type Schedule interface {
+	// Next returns the first activation time strictly after t.
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule parses a cron expression into a Schedule. It accepts:
+//
+//   - standard 5-field expressions: minute hour day-of-month month day-of-week
+//   - 6-field expressions with a leading seconds field
+//   - the descriptors @yearly (@annually), @monthly, @weekly, @daily
+//     (@midnight) and @hourly
+//   - "@every <duration>", e.g. "@every 1h30m"
+//
+// Fields support "*", "?", lists ("1,15"), ranges ("1-5"), steps ("*/10",
+// "0-30/5") and the names JAN-DEC and SUN-SAT. As in standard cron, when
+// both day-of-month and day-of-week are restricted, a day matching either
+// of them is an activation day. Specs that never fire, such as "0 0 30 2 *",
+// are rejected.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron spec %q: %v", spec, err)
+		}
+		if d < time.Second {
+			return nil, fmt.Errorf("invalid cron spec %q: interval must be at least 1s", spec)
+		}
+		return everySchedule{interval: d}, nil
+	}
+
+	switch spec {
+	case "@yearly", "@annually":
+		spec = "0 0 1 1 *"
+	case "@monthly":
+		spec = "0 0 1 * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	case "@daily", "@midnight":
+		spec = "0 0 * * *"
+	case "@hourly":
+		spec = "0 * * * *"
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("invalid cron spec %q: expected 5 or 6 fields, got %d", spec, len(fields))
+	}
+
+	var s cronSchedule
+	var err error
+	for i, f := range []struct {
+		bits *uint64
+		cronField
+	}{
+		{&s.second, secondField},
+		{&s.minute, minuteField},
+		{&s.hour, hourField},
+		{&s.dom, domField},
+		{&s.month, monthField},
+		{&s.dow, dowField},
+	} {
+		if *f.bits, err = f.parse(fields[i]); err != nil {
+			return nil, fmt.Errorf("invalid cron spec %q: %v", spec, err)
+		}
+	}
+	s.domStar = isWildcard(fields[3])
+	s.dowStar = isWildcard(fields[5])
+	if !s.fires() {
+		return nil, fmt.Errorf("invalid cron spec %q: day of month never occurs in the given months", spec)
+	}
+	return &s, nil
+}
+
+// everySchedule activates at a fixed interval.
+type everySchedule struct {
+	interval time.Duration
+}
+
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Add(s.interval).Truncate(time.Second)
+}
+
+// cronSchedule holds one bit per allowed value of every cron field.
+type cronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+
+	// domStar and dowStar record unrestricted day fields, which decides
+	// whether the day fields are combined with AND or OR.
+	domStar, dowStar bool
+}
+
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Second).Add(time.Second)
+
+	// ParseSchedule rejects schedules that never fire. The longest gap
+	// between two activations is the eight years between some leap days,
+	// e.g. for "0 0 29 2 *" across 2100.
+	limit := t.AddDate(9, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+		if s.second&(1<<uint(t.Second())) == 0 {
+			t = t.Add(time.Second)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// monthDays holds the largest day of every month.
+var monthDays = [13]uint{0, 31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// fires reports whether the schedule has any activation. Only a restricted
+// day of month combined with an unrestricted day of week can miss, when
+// none of the days exist in the given months.
+func (s *cronSchedule) fires() bool {
+	if !s.dowStar || s.domStar {
+		return true
+	}
+	for m := uint(1); m <= 12; m++ {
+		if s.month&(1<<m) != 0 && s.dom&(1<<(monthDays[m]+1)-1) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// cronField describes the range and names of a single cron field.
+type cronField struct {
+	name     string
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	secondField = cronField{name: "second", min: 0, max: 59}
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	domField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, names: map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = cronField{name: "day of week", min: 0, max: 7, names: map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+func isWildcard(expr string) bool {
+	return expr == "*" || expr == "?"
+}
+
+// parse returns the bit set of the values matched by expr.
+func (f cronField) parse(expr string) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(expr, ",") {
+		rangeExpr, stepExpr, hasStep := strings.Cut(part, "/")
+
+		var lo, hi uint
+		switch {
+		case isWildcard(rangeExpr):
+			lo, hi = f.min, f.max
+		default:
+			loExpr, hiExpr, isRange := strings.Cut(rangeExpr, "-")
+			var err error
+			if lo, err = f.value(loExpr); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = f.value(hiExpr); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = f.max
+			}
+		}
+		if lo > hi {
+			return 0, fmt.Errorf("invalid %s range %q", f.name, rangeExpr)
+		}
+
+		step := uint(1)
+		if hasStep {
+			n, err := strconv.ParseUint(stepExpr, 10, 8)
+			if err != nil || n == 0 {
+				return 0, fmt.Errorf("invalid %s step %q", f.name, stepExpr)
+			}
+			step = uint(n)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+
+	// Sunday can be written as 0 or 7.
+	if f.name == dowField.name && set&(1<<7) != 0 {
+		set = set&^(1<<7) | 1
+	}
+	if set == 0 {
+		return 0, fmt.Errorf("empty %s field", f.name)
+	}
+	return set, nil
+}
+
+// value parses a single number or name of the field.
+func (f cronField) value(expr string) (uint, error) {
+	if v, ok := f.names[strings.ToLower(expr)]; ok {
+		return v, nil
+	}
+	n, err := strconv.ParseUint(expr, 10, 8)
+	if err != nil || uint(n) < f.min || uint(n) > f.max {
+		return 0, fmt.Errorf("invalid %s %q", f.name, expr)
+	}
+	return uint(n), nil
+}
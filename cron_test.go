@@ -0,0 +1,95 @@
+package tqwp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abdullahnettoor/tqwp"
+)
+
+func date(year int, month time.Month, day, hour, min, sec int) time.Time {
+	return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+}
+
+func TestScheduleNext(t *testing.T) {
+	tests := []struct {
+		spec     string
+		from     time.Time
+		want     time.Time
+		describe string
+	}{
+		{"*/15 * * * *", date(2024, 1, 1, 10, 7, 30), date(2024, 1, 1, 10, 15, 0), "step"},
+		{"0-30/10 9 * * *", date(2024, 1, 1, 9, 25, 0), date(2024, 1, 1, 9, 30, 0), "stepped range"},
+		{"0-30/10 9 * * *", date(2024, 1, 1, 9, 30, 0), date(2024, 1, 2, 9, 0, 0), "strictly after"},
+		{"5/20 * * * *", date(2024, 1, 1, 10, 26, 0), date(2024, 1, 1, 10, 45, 0), "step from a value"},
+		{"0 8,18 * * *", date(2024, 1, 1, 9, 0, 0), date(2024, 1, 1, 18, 0, 0), "list"},
+		{"0 0 1 jul,DEC *", date(2024, 1, 15, 0, 0, 0), date(2024, 7, 1, 0, 0, 0), "month names"},
+		{"0 9 * * mon-fri", date(2024, 1, 6, 0, 0, 0), date(2024, 1, 8, 9, 0, 0), "day names"},
+		{"0 0 * * 7", date(2024, 1, 1, 0, 0, 0), date(2024, 1, 7, 0, 0, 0), "Sunday as 7"},
+		{"0 0 13 * FRI", date(2024, 1, 1, 0, 0, 0), date(2024, 1, 5, 0, 0, 0), "day of week OR day of month"},
+		{"0 0 13 * FRI", date(2024, 1, 12, 0, 0, 0), date(2024, 1, 13, 0, 0, 0), "day of month OR day of week"},
+		{"0 0 31 * *", date(2024, 2, 1, 0, 0, 0), date(2024, 3, 31, 0, 0, 0), "skips short months"},
+		{"0 0 31 * ?", date(2024, 4, 1, 0, 0, 0), date(2024, 5, 31, 0, 0, 0), "question mark"},
+		{"0 0 29 2 *", date(2025, 3, 1, 0, 0, 0), date(2028, 2, 29, 0, 0, 0), "leap day"},
+		{"0 0 29 2 *", date(2097, 3, 1, 0, 0, 0), date(2104, 2, 29, 0, 0, 0), "leap day across 2100"},
+		{"*/20 * * * * *", date(2024, 1, 1, 10, 0, 5), date(2024, 1, 1, 10, 0, 20), "seconds field"},
+		{"30 0 0 * * *", date(2024, 1, 1, 0, 0, 30), date(2024, 1, 2, 0, 0, 30), "seconds field strictly after"},
+		{"@every 90m", date(2024, 1, 1, 10, 0, 0), date(2024, 1, 1, 11, 30, 0), "every"},
+		{"@hourly", date(2024, 1, 1, 10, 0, 0), date(2024, 1, 1, 11, 0, 0), "hourly"},
+		{"@daily", date(2024, 1, 1, 10, 0, 0), date(2024, 1, 2, 0, 0, 0), "daily"},
+		{"@midnight", date(2024, 1, 1, 10, 0, 0), date(2024, 1, 2, 0, 0, 0), "midnight"},
+		{"@weekly", date(2024, 1, 1, 10, 0, 0), date(2024, 1, 7, 0, 0, 0), "weekly"},
+		{"@monthly", date(2024, 1, 31, 10, 0, 0), date(2024, 2, 1, 0, 0, 0), "monthly"},
+		{"@yearly", date(2024, 1, 1, 0, 0, 0), date(2025, 1, 1, 0, 0, 0), "yearly"},
+		{"@annually", date(2024, 6, 1, 0, 0, 0), date(2025, 1, 1, 0, 0, 0), "annually"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.describe, func(t *testing.T) {
+			s, err := tqwp.ParseSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseSchedule(%q): %v", tt.spec, err)
+			}
+			if got := s.Next(tt.from); !got.Equal(tt.want) {
+				t.Fatalf("%q: Next(%v) = %v, want %v", tt.spec, tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"bogus",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"* * * foo *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"@every 10ms",
+		"@every soon",
+		"0 0 30 2 *",
+		"0 0 31 4,6,9,11 *",
+		"0 0 30,31 feb *",
+	} {
+		if _, err := tqwp.ParseSchedule(spec); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestAddCronJobRejectsSpecsThatNeverFire(t *testing.T) {
+	wp := tqwp.New(&tqwp.WorkerPoolConfig{NumOfWorkers: 1, QueueSize: 1, Logger: tqwp.NopLogger{}})
+	factory := func() tqwp.Task { return &walTask{} }
+	if _, err := wp.AddCronJob("0 0 30 2 *", factory, tqwp.OverlapSkip); err == nil {
+		t.Fatal("AddCronJob accepted a spec that never fires")
+	}
+	if jobs := wp.CronJobs(); len(jobs) != 0 {
+		t.Fatalf("%d cron jobs registered, want 0", len(jobs))
+	}
+}
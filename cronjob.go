@@ -0,0 +1,322 @@
+package tqwp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// OverlapPolicy decides what happens when a cron job is due while the task
+// of its previous run is still queued, retrying or running.
+type OverlapPolicy uint8
+
+const (
+	// OverlapSkip skips the new run. It is the default.
+	OverlapSkip OverlapPolicy = iota
+
+	// OverlapQueue enqueues the new run regardless of previous runs.
+	OverlapQueue
+
+	// OverlapReplace cancels the previous runs and enqueues the new one.
+	// A replaced run that is already processing only stops early if its
+	// task implements ContextTask. Replaced runs are counted as cancelled.
+	OverlapReplace
+)
+
+// errRunReplaced is returned by a cron run that was replaced by a newer one.
+var errRunReplaced = fmt.Errorf("%w: replaced by a newer run", ErrScheduleCancelled)
+
+// CronJob is a recurring task registered with WorkerPool.AddCronJob.
+// Every time the job is due, a fresh task is created by its factory and
+// enqueued on the pool.
+type CronJob struct {
+	spec     string
+	schedule Schedule
+	factory  func() Task
+	policy   OverlapPolicy
+	pool     *WorkerPool
+
+	mu      sync.Mutex
+	next    time.Time
+	active  map[*cronRun]struct{}
+	stopped bool
+	stop    chan struct{}
+}
+
+// Spec returns the cron expression the job was registered with.
+func (j *CronJob) Spec() string {
+	return j.spec
+}
+
+// Next returns the next time the job is due.
+func (j *CronJob) Next() time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.next
+}
+
+// Stop removes the job from the pool. Runs already enqueued are not affected.
+func (j *CronJob) Stop() {
+	j.mu.Lock()
+	if !j.stopped {
+		j.stopped = true
+		close(j.stop)
+	}
+	j.mu.Unlock()
+
+	j.pool.cronMu.Lock()
+	defer j.pool.cronMu.Unlock()
+	delete(j.pool.cronJobs, j)
+}
+
+// run enqueues a new task every time the job is due, until the job is
+// stopped or ctx is done.
+func (j *CronJob) run(ctx context.Context) {
+	defer j.pool.wg.Done()
+
+	for {
+		j.mu.Lock()
+		next := j.next
+		j.mu.Unlock()
+		if next.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-j.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		j.mu.Lock()
+		j.next = j.schedule.Next(time.Now())
+		j.mu.Unlock()
+
+		j.fire(ctx)
+	}
+}
+
+// fire enqueues a new run of the job, honouring its overlap policy.
+func (j *CronJob) fire(ctx context.Context) {
+	j.mu.Lock()
+	if len(j.active) > 0 {
+		switch j.policy {
+		case OverlapSkip:
+			j.mu.Unlock()
+			j.pool.logger.Log(LevelWarn, "Cron job skipped: previous run still active", Field{Key: "spec", Value: j.spec})
+			return
+		case OverlapReplace:
+			for r := range j.active {
+				r.replace()
+			}
+		}
+	}
+
+	r := &cronRun{
+		job:  j,
+		task: j.factory(),
+	}
+	j.active[r] = struct{}{}
+	j.mu.Unlock()
+
+	// A run the queue refuses is completed right away, which forgets it.
+	j.pool.enqueueTaskContext(ctx, r)
+}
+
+// done forgets a run that reached its final outcome.
+func (j *CronJob) done(r *cronRun) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.active, r)
+}
+
+// cronRun wraps the task of a single run of a cron job, keeping track of
+// its outcome for the overlap policy.
+type cronRun struct {
+	job  *CronJob
+	task Task
+
+	mu       sync.Mutex
+	retries  uint
+	trace    SpanContext
+	replaced bool
+	cancel   context.CancelFunc
+}
+
+// replace marks the run as replaced and cancels it if it is processing.
+func (r *cronRun) replace() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.replaced = true
+	if r.cancel != nil {
+		r.cancel()
+	}
+}
+
+// Process implements Task.
+func (r *cronRun) Process() error {
+	return r.ProcessContext(context.Background())
+}
+
+// ProcessContext implements ContextTask.
+func (r *cronRun) ProcessContext(ctx context.Context) error {
+	r.mu.Lock()
+	if r.replaced {
+		r.mu.Unlock()
+		return errRunReplaced
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	r.cancel = cancel
+	r.mu.Unlock()
+	defer cancel()
+
+	err := processTask(ctx, r.task)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cancel = nil
+	if err != nil && r.replaced {
+		return fmt.Errorf("%w: %v", errRunReplaced, err)
+	}
+	return err
+}
+
+// Retries implements RetryableTask, deferring to the wrapped task if it
+// keeps its own retry count.
+func (r *cronRun) Retries() uint {
+	if rt, ok := r.task.(RetryableTask); ok {
+		return rt.Retries()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.retries
+}
+
+// SetRetries implements RetryableTask.
+func (r *cronRun) SetRetries(n uint) {
+	if rt, ok := r.task.(RetryableTask); ok {
+		rt.SetRetries(n)
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retries = n
+}
+
+// TraceContext implements TraceableTask, deferring to the wrapped task if
+// it keeps its own span context.
+func (r *cronRun) TraceContext() SpanContext {
+	if tt, ok := r.task.(TraceableTask); ok {
+		return tt.TraceContext()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.trace
+}
+
+// SetTraceContext implements TraceableTask.
+func (r *cronRun) SetTraceContext(sc SpanContext) {
+	if tt, ok := r.task.(TraceableTask); ok {
+		tt.SetTraceContext(sc)
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.trace = sc
+}
+
+// Timeout implements TimeoutTask, deferring to the wrapped task.
+func (r *cronRun) Timeout() time.Duration {
+	return r.job.pool.taskTimeout(r.task)
+}
+
+// Priority implements PriorityTask, deferring to the wrapped task.
+func (r *cronRun) Priority() int {
+	return taskPriority(r.task)
+}
+
+// complete implements completer.
+func (r *cronRun) complete(err error) {
+	r.job.done(r)
+	completeTask(r.task, err)
+}
+
+// String describes the run in log messages.
+func (r *cronRun) String() string {
+	return fmt.Sprintf("cron %q: %v", r.job.spec, r.task)
+}
+
+// AddCronJob registers a recurring job that enqueues a fresh task created by
+// factory every time spec is due. See ParseSchedule for the accepted specs.
+// Jobs start running once the pool is started. Stop stops enqueueing new
+// runs right away and then waits for the runs already enqueued.
+func (wp *WorkerPool) AddCronJob(spec string, factory func() Task, policy OverlapPolicy) (*CronJob, error) {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &CronJob{
+		spec:     spec,
+		schedule: schedule,
+		factory:  factory,
+		policy:   policy,
+		pool:     wp,
+		next:     schedule.Next(time.Now()),
+		active:   make(map[*cronRun]struct{}),
+		stop:     make(chan struct{}),
+	}
+
+	wp.cronMu.Lock()
+	defer wp.cronMu.Unlock()
+
+	wp.cronJobs[j] = struct{}{}
+	if wp.cronCtx != nil {
+		wp.wg.Add(1)
+		go j.run(wp.cronCtx)
+	}
+	return j, nil
+}
+
+// CronJobs returns the cron jobs registered with the pool.
+func (wp *WorkerPool) CronJobs() []*CronJob {
+	wp.cronMu.Lock()
+	defer wp.cronMu.Unlock()
+
+	jobs := make([]*CronJob, 0, len(wp.cronJobs))
+	for j := range wp.cronJobs {
+		jobs = append(jobs, j)
+	}
+	return jobs
+}
+
+// startCron starts running the registered cron jobs until ctx is done or
+// stopCron is called.
+func (wp *WorkerPool) startCron(ctx context.Context) {
+	wp.cronMu.Lock()
+	defer wp.cronMu.Unlock()
+
+	ctx, wp.cronCancel = context.WithCancel(ctx)
+	wp.cronCtx = ctx
+	for j := range wp.cronJobs {
+		wp.wg.Add(1)
+		go j.run(ctx)
+	}
+}
+
+// stopCron stops enqueueing new runs of the cron jobs.
+func (wp *WorkerPool) stopCron() {
+	wp.cronMu.Lock()
+	defer wp.cronMu.Unlock()
+
+	if wp.cronCancel != nil {
+		wp.cronCancel()
+	}
+}
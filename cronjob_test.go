@@ -0,0 +1,64 @@
+package tqwp
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+// countTask counts how many times tasks sharing n are processed.
+type countTask struct {
+	n *int32
+}
+
+func (t *countTask) Process() error {
+	atomic.AddInt32(t.n, 1)
+	return nil
+}
+
+// TestCronOverlapPolicies fires a job twice while its first run is still
+// queued, then checks what each overlap policy enqueued and processed.
+func TestCronOverlapPolicies(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    OverlapPolicy
+		queued    int
+		processed int32
+		cancelled uint32
+	}{
+		{"Skip", OverlapSkip, 1, 1, 0},
+		{"Queue", OverlapQueue, 2, 2, 0},
+		{"Replace", OverlapReplace, 2, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wp := New(&WorkerPoolConfig{NumOfWorkers: 1, QueueSize: 10, Logger: NopLogger{}})
+			processed := new(int32)
+			j, err := wp.AddCronJob("@yearly", func() Task { return &countTask{n: processed} }, tt.policy)
+			if err != nil {
+				t.Fatalf("AddCronJob: %v", err)
+			}
+
+			j.fire(context.Background())
+			j.fire(context.Background())
+			if n := wp.queue.Len(); n != tt.queued {
+				t.Fatalf("%d runs queued, want %d", n, tt.queued)
+			}
+
+			wp.Start()
+			wp.Stop()
+
+			if n := atomic.LoadInt32(processed); n != tt.processed {
+				t.Fatalf("%d runs processed, want %d", n, tt.processed)
+			}
+			if wp.TaskCancelled != tt.cancelled {
+				t.Fatalf("TaskCancelled = %d, want %d", wp.TaskCancelled, tt.cancelled)
+			}
+			j.mu.Lock()
+			defer j.mu.Unlock()
+			if len(j.active) != 0 {
+				t.Fatalf("%d runs still active after the pool stopped", len(j.active))
+			}
+		})
+	}
+}
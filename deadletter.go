@@ -0,0 +1,195 @@
+package tqwp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrDeadLetterNotFound is returned when a dead letter does not exist.
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetter is a task the pool gave up on, either because it exhausted its
+// retries or because it failed with a permanent error.
+type DeadLetter struct {
+	// ID identifies the dead letter within its sink. It is assigned by Put.
+	ID uint64
+
+	// Task is the task that failed. It may be nil for dead letters loaded
+	// from persistent storage when the task could not be decoded.
+	Task Task
+
+	// Err is the error returned by the last attempt.
+	Err error
+
+	// Attempts is the number of times the task was processed.
+	Attempts uint
+
+	// LastAttemptAt is the time the last attempt started.
+	LastAttemptAt time.Time
+
+	// FailedAt is the time the task was dead-lettered.
+	FailedAt time.Time
+}
+
+// DeadLetterSink stores tasks that the pool gave up on.
+// Implementations must be safe for concurrent use.
+type DeadLetterSink interface {
+	// Put stores dl and assigns its ID.
+	Put(dl *DeadLetter) error
+
+	// Get returns the dead letter with the given ID.
+	Get(id uint64) (*DeadLetter, error)
+
+	// List returns all dead letters ordered by ID.
+	List() ([]*DeadLetter, error)
+
+	// Remove deletes the dead letter with the given ID.
+	Remove(id uint64) error
+}
+
+// MemoryDeadLetterSink keeps dead letters in memory.
+// It is the sink used when WorkerPoolConfig.DeadLetters is not set.
+type MemoryDeadLetterSink struct {
+	mu      sync.Mutex
+	lastID  uint64
+	letters map[uint64]*DeadLetter
+}
+
+// NewMemoryDeadLetterSink returns an empty MemoryDeadLetterSink.
+func NewMemoryDeadLetterSink() *MemoryDeadLetterSink {
+	return &MemoryDeadLetterSink{
+		letters: make(map[uint64]*DeadLetter),
+	}
+}
+
+// Put implements DeadLetterSink.
+func (s *MemoryDeadLetterSink) Put(dl *DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	dl.ID = s.lastID
+	s.letters[dl.ID] = dl
+	return nil
+}
+
+// Get implements DeadLetterSink.
+func (s *MemoryDeadLetterSink) Get(id uint64) (*DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dl, ok := s.letters[id]
+	if !ok {
+		return nil, ErrDeadLetterNotFound
+	}
+	return dl, nil
+}
+
+// List implements DeadLetterSink.
+func (s *MemoryDeadLetterSink) List() ([]*DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return sortedDeadLetters(s.letters), nil
+}
+
+// Remove implements DeadLetterSink.
+func (s *MemoryDeadLetterSink) Remove(id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.letters[id]; !ok {
+		return ErrDeadLetterNotFound
+	}
+	delete(s.letters, id)
+	return nil
+}
+
+// sortedDeadLetters returns the dead letters of m ordered by ID.
+func sortedDeadLetters(m map[uint64]*DeadLetter) []*DeadLetter {
+	letters := make([]*DeadLetter, 0, len(m))
+	for _, dl := range m {
+		letters = append(letters, dl)
+	}
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].ID < letters[j].ID
+	})
+	return letters
+}
+
+// DeadLetters returns the tasks the pool gave up on, ordered by ID.
+func (wp *WorkerPool) DeadLetters() ([]*DeadLetter, error) {
+	return wp.deadLetters.List()
+}
+
+// DeadLetter returns the dead letter with the given ID.
+func (wp *WorkerPool) DeadLetter(id uint64) (*DeadLetter, error) {
+	return wp.deadLetters.Get(id)
+}
+
+// RequeueDeadLetter removes the dead letter with the given ID from the sink
+// and enqueues its task again with a fresh retry count, applying the
+// overflow policy of the pool, except that OverflowDropNewest rejects the
+// task instead of discarding it.
+//
+// If the task is refused, for example because the queue is full or the
+// pool was stopped, the dead letter is put back in the sink under a new ID
+// and the error is returned.
+func (wp *WorkerPool) RequeueDeadLetter(id uint64) error {
+	dl, err := wp.deadLetters.Get(id)
+	if err != nil {
+		return err
+	}
+	if dl.Task == nil {
+		return fmt.Errorf("dead letter %d has no runnable task", id)
+	}
+	// Removing the dead letter first ensures concurrent calls enqueue its
+	// task only once.
+	if err := wp.deadLetters.Remove(id); err != nil {
+		return err
+	}
+
+	retries := uint(0)
+	rt, retryable := dl.Task.(RetryableTask)
+	if retryable {
+		retries = rt.Retries()
+		rt.SetRetries(0)
+	}
+	policy := wp.overflow
+	if policy == OverflowDropNewest {
+		policy = OverflowReject
+	}
+	err = wp.enqueuePolicy(context.Background(), dl.Task, policy)
+	if err == nil {
+		return nil
+	}
+
+	if retryable {
+		rt.SetRetries(retries)
+	}
+	if putErr := wp.deadLetters.Put(dl); putErr != nil {
+		return fmt.Errorf("%w, and failed to restore dead letter %d: %v", err, id, putErr)
+	}
+	return err
+}
+
+// deadLetter hands a task the pool gave up on to the dead-letter sink.
+func (wp *WorkerPool) deadLetter(task Task, err error, attempts uint, startedAt time.Time) {
+	dl := &DeadLetter{
+		Task:          task,
+		Err:           err,
+		Attempts:      attempts,
+		LastAttemptAt: startedAt,
+		FailedAt:      time.Now(),
+	}
+	if err := wp.deadLetters.Put(dl); err != nil {
+		wp.logger.Log(LevelError, "Failed to dead-letter task",
+			Field{Key: "task", Value: taskID(task)},
+			Field{Key: "error", Value: err},
+		)
+	}
+}
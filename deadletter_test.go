@@ -0,0 +1,152 @@
+package tqwp_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/abdullahnettoor/tqwp"
+)
+
+// unregisteredTask is a task whose type is not registered.
+type unregisteredTask struct {
+	tqwp.TaskModel
+	N int
+}
+
+func (t *unregisteredTask) Process() error { return nil }
+
+func openDeadLetters(t *testing.T, path string, reg *tqwp.Registry) *tqwp.FileDeadLetterSink {
+	t.Helper()
+	sink, err := tqwp.NewFileDeadLetterSink(path, reg)
+	if err != nil {
+		t.Fatalf("NewFileDeadLetterSink: %v", err)
+	}
+	return sink
+}
+
+func TestFileDeadLetterSinkReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	sink := openDeadLetters(t, path, walRegistry())
+	for _, dl := range []*tqwp.DeadLetter{
+		{Task: &walTask{N: 1}, Err: errors.New("first"), Attempts: 3},
+		{Task: &unregisteredTask{N: 2}, Err: errors.New("second")},
+		{Task: &walTask{N: 3}, Err: errors.New("third")},
+	} {
+		if err := sink.Put(dl); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	if err := sink.Remove(3); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	sink.Close()
+
+	sink = openDeadLetters(t, path, walRegistry())
+	defer sink.Close()
+	letters, err := sink.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(letters) != 2 {
+		t.Fatalf("loaded %d dead letters, want 2", len(letters))
+	}
+
+	first := letters[0]
+	task, ok := first.Task.(*walTask)
+	if !ok || task.N != 1 {
+		t.Fatalf("first dead letter carries task %#v, want the registered task", first.Task)
+	}
+	if first.Err.Error() != "first" || first.Attempts != 3 {
+		t.Fatalf("first dead letter is %v after %d attempts", first.Err, first.Attempts)
+	}
+
+	// The task of an unregistered type cannot be rebuilt, but its dead
+	// letter is still loaded.
+	if second := letters[1]; second.Task != nil || second.Err.Error() != "second" {
+		t.Fatalf("second dead letter carries task %#v and error %v", second.Task, second.Err)
+	}
+}
+
+func TestFileDeadLetterSinkWithoutRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	sink := openDeadLetters(t, path, nil)
+	sink.Put(&tqwp.DeadLetter{Task: &walTask{N: 1}, Err: errors.New("failed")})
+	sink.Close()
+
+	sink = openDeadLetters(t, path, nil)
+	defer sink.Close()
+	dl, err := sink.Get(1)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if dl.Task != nil {
+		t.Fatalf("dead letter loaded without a registry carries task %#v", dl.Task)
+	}
+}
+
+func TestRequeueDeadLetter(t *testing.T) {
+	tests := []struct {
+		name     string
+		overflow tqwp.OverflowPolicy
+		full     bool
+		want     error
+	}{
+		{"Room", tqwp.OverflowReject, false, nil},
+		{"Reject", tqwp.OverflowReject, true, tqwp.ErrQueueFull},
+		{"DropNewest", tqwp.OverflowDropNewest, true, tqwp.ErrQueueFull},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := tqwp.NewMemoryDeadLetterSink()
+			wp := tqwp.New(&tqwp.WorkerPoolConfig{
+				QueueSize:   1,
+				Overflow:    tt.overflow,
+				DeadLetters: sink,
+				Logger:      tqwp.NopLogger{},
+			})
+			if tt.full {
+				wp.EnqueueTask(&walTask{})
+			}
+			task := &walTask{N: 1}
+			task.SetRetries(3)
+			sink.Put(&tqwp.DeadLetter{Task: task, Err: errors.New("failed")})
+
+			err := wp.RequeueDeadLetter(1)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("RequeueDeadLetter = %v, want %v", err, tt.want)
+			}
+			letters, _ := wp.DeadLetters()
+			if tt.want == nil {
+				if len(letters) != 0 || task.Retries() != 0 {
+					t.Fatalf("%d dead letters left, task has %d retries, want 0, 0", len(letters), task.Retries())
+				}
+				return
+			}
+			if len(letters) != 1 || letters[0].Task != task || task.Retries() != 3 {
+				t.Fatalf("the refused dead letter was not put back unchanged")
+			}
+		})
+	}
+}
+
+func TestRequeueDeadLetterAfterStop(t *testing.T) {
+	sink := tqwp.NewMemoryDeadLetterSink()
+	wp := tqwp.New(&tqwp.WorkerPoolConfig{
+		NumOfWorkers: 1,
+		QueueSize:    1,
+		DeadLetters:  sink,
+		Logger:       tqwp.NopLogger{},
+	})
+	wp.Start()
+	stopWithin(t, wp, time.Minute)
+
+	sink.Put(&tqwp.DeadLetter{Task: &walTask{}})
+	if err := wp.RequeueDeadLetter(1); !errors.Is(err, tqwp.ErrQueueClosed) {
+		t.Fatalf("RequeueDeadLetter = %v, want %v", err, tqwp.ErrQueueClosed)
+	}
+	if letters, _ := wp.DeadLetters(); len(letters) != 1 {
+		t.Fatalf("%d dead letters left, want 1", len(letters))
+	}
+}
@@ -0,0 +1,206 @@
+package tqwp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// deadLetterRecord is a single line of the dead-letter file.
+type deadLetterRecord struct {
+	ID            uint64          `json:"id"`
+	Removed       bool            `json:"removed,omitempty"`
+	TaskType      string          `json:"task_type,omitempty"`
+	Task          json.RawMessage `json:"task,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	Attempts      uint            `json:"attempts,omitempty"`
+	LastAttemptAt *time.Time      `json:"last_attempt_at,omitempty"`
+	FailedAt      *time.Time      `json:"failed_at,omitempty"`
+}
+
+// FileDeadLetterSink appends dead letters to a file in JSON lines format.
+// Removals are appended as tombstone records, so the file is a complete
+// audit log of dead-lettered tasks. Existing records are loaded on open.
+//
+// Tasks are encoded with JSONCodec, along with the name their type was
+// registered under with the sink's Registry, or their Go type if it was not
+// registered. Dead letters loaded from the file only carry a runnable Task
+// if its type is registered with the Registry.
+type FileDeadLetterSink struct {
+	mu       sync.Mutex
+	file     *os.File
+	registry *Registry
+	lastID   uint64
+	letters  map[uint64]*DeadLetter
+}
+
+// NewFileDeadLetterSink opens or creates the dead-letter file at path and
+// loads the dead letters it already holds. registry may be nil, in which
+// case loaded dead letters carry no Task.
+func NewFileDeadLetterSink(path string, registry *Registry) (*FileDeadLetterSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead-letter file %s: %w", path, err)
+	}
+
+	s := &FileDeadLetterSink{
+		file:     file,
+		registry: registry,
+		letters:  make(map[uint64]*DeadLetter),
+	}
+	if err := s.load(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to load dead-letter file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// load replays the records of the file into memory.
+func (s *FileDeadLetterSink) load() error {
+	scanner := bufio.NewScanner(s.file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var rec deadLetterRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return err
+		}
+		if rec.ID > s.lastID {
+			s.lastID = rec.ID
+		}
+		if rec.Removed {
+			delete(s.letters, rec.ID)
+			continue
+		}
+
+		dl := &DeadLetter{
+			ID:       rec.ID,
+			Err:      errors.New(rec.Error),
+			Attempts: rec.Attempts,
+		}
+		if rec.LastAttemptAt != nil {
+			dl.LastAttemptAt = *rec.LastAttemptAt
+		}
+		if rec.FailedAt != nil {
+			dl.FailedAt = *rec.FailedAt
+		}
+		if s.registry != nil && len(rec.Task) > 0 {
+			// A task that cannot be decoded leaves the dead letter
+			// without a Task rather than the sink unusable.
+			env := &Envelope{Type: rec.TaskType, Payload: rec.Task}
+			if task, err := s.registry.Open(JSONCodec, env); err == nil {
+				dl.Task = task
+			}
+		}
+		s.letters[rec.ID] = dl
+	}
+	return scanner.Err()
+}
+
+// append writes rec as a new line of the file.
+func (s *FileDeadLetterSink) append(rec *deadLetterRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// Put implements DeadLetterSink.
+func (s *FileDeadLetterSink) Put(dl *DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec := &deadLetterRecord{
+		ID:            s.lastID + 1,
+		Attempts:      dl.Attempts,
+		LastAttemptAt: &dl.LastAttemptAt,
+		FailedAt:      &dl.FailedAt,
+	}
+	if dl.Err != nil {
+		rec.Error = dl.Err.Error()
+	}
+	if dl.Task != nil {
+		taskType, data, err := s.encodeTask(dl.Task)
+		if err != nil {
+			return fmt.Errorf("failed to encode task: %w", err)
+		}
+		rec.TaskType = taskType
+		rec.Task = data
+	}
+	if err := s.append(rec); err != nil {
+		return err
+	}
+
+	s.lastID = rec.ID
+	dl.ID = rec.ID
+	s.letters[dl.ID] = dl
+	return nil
+}
+
+// encodeTask returns the type name and the JSON encoding of task.
+func (s *FileDeadLetterSink) encodeTask(task Task) (string, []byte, error) {
+	if s.registry != nil {
+		env, err := s.registry.Seal(JSONCodec, task)
+		if err == nil {
+			return env.Type, env.Payload, nil
+		}
+		if !errors.Is(err, ErrUnregisteredTask) {
+			return "", nil, err
+		}
+	}
+	data, err := JSONCodec.Marshal(task)
+	return fmt.Sprintf("%T", task), data, err
+}
+
+// Get implements DeadLetterSink.
+func (s *FileDeadLetterSink) Get(id uint64) (*DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dl, ok := s.letters[id]
+	if !ok {
+		return nil, ErrDeadLetterNotFound
+	}
+	return dl, nil
+}
+
+// List implements DeadLetterSink.
+func (s *FileDeadLetterSink) List() ([]*DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return sortedDeadLetters(s.letters), nil
+}
+
+// Remove implements DeadLetterSink.
+func (s *FileDeadLetterSink) Remove(id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.letters[id]; !ok {
+		return ErrDeadLetterNotFound
+	}
+	if err := s.append(&deadLetterRecord{ID: id, Removed: true}); err != nil {
+		return err
+	}
+	delete(s.letters, id)
+	return nil
+}
+
+// Close closes the underlying file.
+func (s *FileDeadLetterSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
@@ -0,0 +1,589 @@
+package tqwp
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultSegmentSize = 16 << 20
+	defaultMaxSegments = 4
+	segmentExt         = ".wal"
+)
+
+// Write-ahead log record operations.
+const (
+	walPush byte = iota + 1
+	walNack
+	walAck
+)
+
+// DurableQueueConfig holds configuration parameters for DurableQueue.
+type DurableQueueConfig struct {
+	// Dir is the directory holding the write-ahead log segments.
+	// It is created if it does not exist.
+	Dir string
+
+	// Registry is used to encode and decode tasks.
+	// It defaults to DefaultRegistry.
+	Registry *Registry
+
+	// Codec encodes the tasks written to the log. It defaults to JSONCodec.
+	// A log must always be reopened with the codec it was written with.
+	Codec Codec
+
+	// Size specifies the maximum number of tasks waiting in the queue.
+	// Zero means no limit.
+	Size uint
+
+	// SegmentSize specifies the size in bytes after which the log rotates
+	// to a new segment. It defaults to 16 MiB.
+	SegmentSize int64
+
+	// MaxSegments specifies the number of segments after which the log is
+	// compacted into a single segment holding only the unacknowledged
+	// tasks. It defaults to 4.
+	MaxSegments int
+
+	// SyncWrites makes every log write wait for the data to reach the disk.
+	// It is much slower, but no acknowledged write is lost on power failure.
+	SyncWrites bool
+}
+
+// walEntry is an unacknowledged task of a DurableQueue.
+type walEntry struct {
+	id   uint64
+	task Task
+	// data holds the task as last written to the log.
+	data []byte
+}
+
+// DurableQueue is a QueueBackend that survives process restarts. Every
+// pushed task is appended to an on-disk write-ahead log before it is handed
+// out, and is only forgotten once the pool acknowledges its final outcome.
+// The retry count recorded when a task fails is kept as well, so tasks
+// waiting for a retry are not lost either.
+//
+// When a DurableQueue is opened, the tasks left unacknowledged by a previous
+// run are decoded with the registry and queued again, so a pool created with
+// the queue picks them up as soon as it starts.
+//
+// Tasks must be registered with the registry and must be comparable,
+// which pointers to task structs always are.
+type DurableQueue struct {
+	dir         string
+	registry    *Registry
+	codec       Codec
+	size        int
+	segmentSize int64
+	maxSegments int
+	syncWrites  bool
+
+	mu       sync.Mutex
+	wake     chan struct{}
+	closed   bool
+	lastID   uint64
+	entries  map[uint64]*walEntry
+	inflight map[Task]*walEntry
+	ready    []*walEntry
+
+	segments []uint64
+	segment  *os.File
+	writer   *bufio.Writer
+	written  int64
+}
+
+// OpenDurableQueue opens the durable queue stored in cfg.Dir, replaying the
+// tasks that were not acknowledged before the queue was last closed.
+func OpenDurableQueue(cfg *DurableQueueConfig) (*DurableQueue, error) {
+	dq := &DurableQueue{
+		dir:         cfg.Dir,
+		registry:    cfg.Registry,
+		codec:       cfg.Codec,
+		size:        int(cfg.Size),
+		segmentSize: cfg.SegmentSize,
+		maxSegments: cfg.MaxSegments,
+		syncWrites:  cfg.SyncWrites,
+		wake:        make(chan struct{}),
+		entries:     make(map[uint64]*walEntry),
+		inflight:    make(map[Task]*walEntry),
+	}
+	if dq.registry == nil {
+		dq.registry = DefaultRegistry
+	}
+	if dq.codec == nil {
+		dq.codec = JSONCodec
+	}
+	if dq.segmentSize <= 0 {
+		dq.segmentSize = defaultSegmentSize
+	}
+	if dq.maxSegments <= 0 {
+		dq.maxSegments = defaultMaxSegments
+	}
+
+	if err := os.MkdirAll(dq.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory %s: %w", dq.dir, err)
+	}
+	if err := dq.replay(); err != nil {
+		return nil, err
+	}
+
+	// Always append to a fresh segment, so a record torn by a crash at the
+	// end of the last segment is never followed by valid records.
+	if len(dq.segments) >= dq.maxSegments {
+		if err := dq.compact(); err != nil {
+			return nil, err
+		}
+	} else if err := dq.rotate(); err != nil {
+		return nil, err
+	}
+	return dq, nil
+}
+
+// segmentPath returns the path of the segment with the given sequence number.
+func (dq *DurableQueue) segmentPath(seq uint64) string {
+	return filepath.Join(dq.dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+}
+
+// replay rebuilds the unacknowledged tasks from the log segments.
+func (dq *DurableQueue) replay() error {
+	files, err := os.ReadDir(dq.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read queue directory %s: %w", dq.dir, err)
+	}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		dq.segments = append(dq.segments, seq)
+	}
+	sort.Slice(dq.segments, func(i, j int) bool {
+		return dq.segments[i] < dq.segments[j]
+	})
+
+	for _, seq := range dq.segments {
+		if err := dq.replaySegment(dq.segmentPath(seq)); err != nil {
+			return err
+		}
+	}
+
+	ids := make([]uint64, 0, len(dq.entries))
+	for id := range dq.entries {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		e := dq.entries[id]
+		task, err := dq.registry.Unmarshal(dq.codec, e.data)
+		if err != nil {
+			return fmt.Errorf("failed to replay task %d: %w", id, err)
+		}
+		e.task = task
+		dq.ready = append(dq.ready, e)
+	}
+	return nil
+}
+
+// replaySegment applies the records of a single segment. Reading stops at
+// the first incomplete or corrupted record, which can only be the result of
+// a crash in the middle of a write.
+func (dq *DurableQueue) replaySegment(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open segment %s: %w", path, err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		payload, err := readFrame(r)
+		if err != nil {
+			return nil
+		}
+
+		if len(payload) == 0 {
+			return nil
+		}
+		op := payload[0]
+		id, n := binary.Uvarint(payload[1:])
+		if n <= 0 {
+			return nil
+		}
+		data := payload[1+n:]
+		if id > dq.lastID {
+			dq.lastID = id
+		}
+
+		switch op {
+		case walPush:
+			dq.entries[id] = &walEntry{id: id, data: data}
+		case walNack:
+			if e, ok := dq.entries[id]; ok {
+				e.data = data
+			}
+		case walAck:
+			delete(dq.entries, id)
+		}
+	}
+}
+
+// readFrame reads a record framed as a big-endian length, a CRC-32 checksum
+// and the payload. The payload holds the operation, the task id and, for
+// pushes and nacks, the task encoded by Registry.Marshal.
+func readFrame(r io.Reader) ([]byte, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(header[:4]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, errors.New("checksum mismatch")
+	}
+	return payload, nil
+}
+
+// append writes a record to the current segment. If the segment is full,
+// the log is rotated, or compacted, before the record is written rather
+// than after: compaction rewrites the log from dq.entries, which the caller
+// only updates once the record is written, so compacting after the write
+// would drop the record. It must be called with dq.mu held.
+func (dq *DurableQueue) append(op byte, id uint64, data []byte) error {
+	if dq.written >= dq.segmentSize {
+		var err error
+		if len(dq.segments) >= dq.maxSegments {
+			err = dq.compact()
+		} else {
+			err = dq.rotate()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return dq.write(op, id, data)
+}
+
+// write frames a record into the current segment and flushes it.
+func (dq *DurableQueue) write(op byte, id uint64, data []byte) error {
+	payload := binary.AppendUvarint([]byte{op}, id)
+	payload = append(payload, data...)
+
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(header[4:], crc32.ChecksumIEEE(payload))
+	if _, err := dq.writer.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := dq.writer.Write(payload); err != nil {
+		return err
+	}
+	if err := dq.writer.Flush(); err != nil {
+		return err
+	}
+	if dq.syncWrites {
+		if err := dq.segment.Sync(); err != nil {
+			return err
+		}
+	}
+	dq.written += int64(len(header) + len(payload))
+	return nil
+}
+
+// rotate closes the current segment and starts a new one.
+func (dq *DurableQueue) rotate() error {
+	if err := dq.closeSegment(); err != nil {
+		return err
+	}
+
+	seq := uint64(1)
+	if n := len(dq.segments); n > 0 {
+		seq = dq.segments[n-1] + 1
+	}
+	f, err := os.OpenFile(dq.segmentPath(seq), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create segment: %w", err)
+	}
+
+	dq.segments = append(dq.segments, seq)
+	dq.segment = f
+	dq.writer = bufio.NewWriter(f)
+	dq.written = 0
+	return nil
+}
+
+// closeSegment syncs and closes the current segment, if any.
+func (dq *DurableQueue) closeSegment() error {
+	if dq.segment == nil {
+		return nil
+	}
+	if err := dq.writer.Flush(); err != nil {
+		return err
+	}
+	if err := dq.segment.Sync(); err != nil {
+		return err
+	}
+	err := dq.segment.Close()
+	dq.segment = nil
+	dq.writer = nil
+	return err
+}
+
+// compact writes the unacknowledged tasks into a new segment and removes
+// all older segments. Replaying records is idempotent, so a crash during
+// compaction leaves a log that still replays to the same tasks.
+func (dq *DurableQueue) compact() error {
+	old := dq.segments
+	if err := dq.rotate(); err != nil {
+		return err
+	}
+
+	ids := make([]uint64, 0, len(dq.entries))
+	for id := range dq.entries {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		e := dq.entries[id]
+		if err := dq.write(walPush, e.id, e.data); err != nil {
+			return err
+		}
+	}
+	if err := dq.segment.Sync(); err != nil {
+		return err
+	}
+
+	for _, seq := range old {
+		if err := os.Remove(dq.segmentPath(seq)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove compacted segment: %w", err)
+		}
+	}
+	dq.segments = dq.segments[len(old):]
+	return nil
+}
+
+// Compact rewrites the log so it holds only the unacknowledged tasks.
+// The log is also compacted automatically once it has MaxSegments segments.
+func (dq *DurableQueue) Compact() error {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+
+	if dq.closed {
+		return ErrQueueClosed
+	}
+	return dq.compact()
+}
+
+// broadcast wakes up every goroutine waiting for the queue to change.
+// It must be called with dq.mu held.
+func (dq *DurableQueue) broadcast() {
+	close(dq.wake)
+	dq.wake = make(chan struct{})
+}
+
+// Push implements QueueBackend. The task is written to the log before Push
+// returns. Pushing a task that was popped and nacked before, as the pool
+// does for retries, keeps its place in the log instead of adding a new one.
+func (dq *DurableQueue) Push(ctx context.Context, task Task) error {
+	if task == nil || !reflect.TypeOf(task).Comparable() {
+		return fmt.Errorf("durable queue: task %T is not comparable", task)
+	}
+
+	dq.mu.Lock()
+	for !dq.closed && dq.size > 0 && len(dq.ready) >= dq.size {
+		wake := dq.wake
+		dq.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		dq.mu.Lock()
+	}
+	defer dq.mu.Unlock()
+
+	if dq.closed {
+		return ErrQueueClosed
+	}
+	return dq.push(task)
+}
+
+// TryPush implements TryPusher.
+func (dq *DurableQueue) TryPush(task Task) error {
+	if task == nil || !reflect.TypeOf(task).Comparable() {
+		return fmt.Errorf("durable queue: task %T is not comparable", task)
+	}
+
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+
+	if dq.closed {
+		return ErrQueueClosed
+	}
+	if dq.size > 0 && len(dq.ready) >= dq.size {
+		return ErrQueueFull
+	}
+	return dq.push(task)
+}
+
+// push writes task to the log and queues it. It must be called with dq.mu held.
+func (dq *DurableQueue) push(task Task) error {
+	e, retried := dq.inflight[task]
+	if !retried {
+		e = &walEntry{id: dq.lastID + 1, task: task}
+	}
+	data, err := dq.registry.Marshal(dq.codec, task)
+	if err != nil {
+		return err
+	}
+	if err := dq.append(walPush, e.id, data); err != nil {
+		return err
+	}
+
+	if retried {
+		delete(dq.inflight, task)
+	} else {
+		dq.lastID = e.id
+	}
+	e.data = data
+	dq.entries[e.id] = e
+	dq.ready = append(dq.ready, e)
+	dq.broadcast()
+	return nil
+}
+
+// EvictOldest implements Evicter. The evicted task is removed from the log
+// as if it had been acknowledged.
+func (dq *DurableQueue) EvictOldest() (Task, bool) {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+
+	if len(dq.ready) == 0 || dq.closed {
+		return nil, false
+	}
+	e := dq.ready[0]
+	if err := dq.append(walAck, e.id, nil); err != nil {
+		return nil, false
+	}
+	dq.ready[0] = nil
+	dq.ready = dq.ready[1:]
+	delete(dq.entries, e.id)
+	dq.broadcast()
+	return e.task, true
+}
+
+// Pop implements QueueBackend.
+func (dq *DurableQueue) Pop(ctx context.Context) (Task, error) {
+	dq.mu.Lock()
+	for len(dq.ready) == 0 {
+		if dq.closed {
+			dq.mu.Unlock()
+			return nil, ErrQueueClosed
+		}
+		wake := dq.wake
+		dq.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		dq.mu.Lock()
+	}
+	defer dq.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	e := dq.ready[0]
+	dq.ready[0] = nil
+	dq.ready = dq.ready[1:]
+	dq.inflight[e.task] = e
+	dq.broadcast()
+	return e.task, nil
+}
+
+// Ack implements QueueBackend. It records in the log that task is done.
+func (dq *DurableQueue) Ack(task Task) error {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+
+	e, ok := dq.inflight[task]
+	if !ok {
+		return nil
+	}
+	if dq.closed {
+		return ErrQueueClosed
+	}
+	if err := dq.append(walAck, e.id, nil); err != nil {
+		return err
+	}
+	delete(dq.inflight, task)
+	delete(dq.entries, e.id)
+	return nil
+}
+
+// Nack implements QueueBackend. It records the current retry state of task
+// in the log, so the task is replayed with it if the process stops before
+// the task is pushed again. After Close, Nack leaves the log unchanged and
+// the task is replayed with the state it was last pushed with.
+func (dq *DurableQueue) Nack(task Task, err error) error {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+
+	e, ok := dq.inflight[task]
+	if !ok || dq.closed {
+		return nil
+	}
+	data, encErr := dq.registry.Marshal(dq.codec, task)
+	if encErr != nil {
+		return encErr
+	}
+	if err := dq.append(walNack, e.id, data); err != nil {
+		return err
+	}
+	e.data = data
+	return nil
+}
+
+// Len implements QueueBackend.
+func (dq *DurableQueue) Len() int {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	return len(dq.ready)
+}
+
+// Close implements QueueBackend. It flushes and closes the log. Tasks still
+// queued can be popped, and are replayed when the queue is opened again.
+func (dq *DurableQueue) Close() error {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+
+	if dq.closed {
+		return nil
+	}
+	dq.closed = true
+	dq.broadcast()
+	return dq.closeSegment()
+}
@@ -0,0 +1,172 @@
+package tqwp_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/abdullahnettoor/tqwp"
+)
+
+// walTask is a task persisted by the durable queue tests.
+type walTask struct {
+	tqwp.TaskModel
+	N int
+}
+
+func (t *walTask) Process() error { return nil }
+
+func walRegistry() *tqwp.Registry {
+	reg := tqwp.NewRegistry()
+	reg.Register("wal", func() tqwp.Task { return &walTask{} })
+	return reg
+}
+
+var walCodecs = []struct {
+	name  string
+	codec tqwp.Codec
+}{
+	{"JSON", tqwp.JSONCodec},
+	{"Gob", tqwp.GobCodec},
+	{"Binary", tqwp.BinaryCodec},
+}
+
+// openWAL opens the durable queue in dir, failing the test on error.
+func openWAL(t *testing.T, dir string, codec tqwp.Codec, segmentSize int64, maxSegments int) *tqwp.DurableQueue {
+	t.Helper()
+	dq, err := tqwp.OpenDurableQueue(&tqwp.DurableQueueConfig{
+		Dir:         dir,
+		Registry:    walRegistry(),
+		Codec:       codec,
+		SegmentSize: segmentSize,
+		MaxSegments: maxSegments,
+	})
+	if err != nil {
+		t.Fatalf("OpenDurableQueue: %v", err)
+	}
+	return dq
+}
+
+// popAll pops every task queued in dq.
+func popAll(t *testing.T, dq *tqwp.DurableQueue) []*walTask {
+	t.Helper()
+	var tasks []*walTask
+	for dq.Len() > 0 {
+		task, err := dq.Pop(context.Background())
+		if err != nil {
+			t.Fatalf("Pop: %v", err)
+		}
+		tasks = append(tasks, task.(*walTask))
+	}
+	return tasks
+}
+
+func TestDurableQueuePushAcrossCompaction(t *testing.T) {
+	for _, c := range walCodecs {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			dq := openWAL(t, dir, c.codec, 100, 2)
+			for i := 0; i < 40; i++ {
+				if err := dq.Push(context.Background(), &walTask{N: i}); err != nil {
+					t.Fatalf("Push %d: %v", i, err)
+				}
+			}
+			dq.Close()
+
+			dq = openWAL(t, dir, c.codec, 100, 2)
+			defer dq.Close()
+			tasks := popAll(t, dq)
+			if len(tasks) != 40 {
+				t.Fatalf("replayed %d tasks, want 40", len(tasks))
+			}
+			for i, task := range tasks {
+				if task.N != i {
+					t.Fatalf("task %d replayed as %d", i, task.N)
+				}
+			}
+		})
+	}
+}
+
+func TestDurableQueueNackAcrossCompaction(t *testing.T) {
+	for _, c := range walCodecs {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			dq := openWAL(t, dir, c.codec, 1, 2)
+			for i := 0; i < 3; i++ {
+				dq.Push(context.Background(), &walTask{N: i})
+			}
+			for i, task := range popAll(t, dq) {
+				task.SetRetries(uint(i + 5))
+				if err := dq.Nack(task, nil); err != nil {
+					t.Fatalf("Nack: %v", err)
+				}
+			}
+			dq.Close()
+
+			dq = openWAL(t, dir, c.codec, 1, 2)
+			defer dq.Close()
+			tasks := popAll(t, dq)
+			if len(tasks) != 3 {
+				t.Fatalf("replayed %d tasks, want 3", len(tasks))
+			}
+			for i, task := range tasks {
+				if task.N != i || task.Retries() != uint(i+5) {
+					t.Fatalf("task %d replayed as %d with %d retries, want %d retries", i, task.N, task.Retries(), i+5)
+				}
+			}
+		})
+	}
+}
+
+func TestDurableQueueAckAcrossRotation(t *testing.T) {
+	for _, c := range walCodecs {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			dq := openWAL(t, dir, c.codec, 64, 3)
+			for i := 0; i < 20; i++ {
+				dq.Push(context.Background(), &walTask{N: i})
+			}
+			for _, task := range popAll(t, dq) {
+				if task.N%2 == 0 {
+					if err := dq.Ack(task); err != nil {
+						t.Fatalf("Ack: %v", err)
+					}
+				}
+			}
+			// The odd tasks are still in flight, and replayed on reopen.
+			dq.Close()
+
+			dq = openWAL(t, dir, c.codec, 64, 3)
+			defer dq.Close()
+			tasks := popAll(t, dq)
+			if len(tasks) != 10 {
+				t.Fatalf("replayed %d tasks, want 10", len(tasks))
+			}
+			for i, task := range tasks {
+				if task.N != 2*i+1 {
+					t.Fatalf("replayed task %d is %d, want %d", i, task.N, 2*i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestDurableQueueRetryKeepsPlace(t *testing.T) {
+	dir := t.TempDir()
+	dq := openWAL(t, dir, tqwp.JSONCodec, 1, 2)
+	dq.Push(context.Background(), &walTask{N: 1})
+	task := popAll(t, dq)[0]
+	task.SetRetries(2)
+	dq.Nack(task, nil)
+	if err := dq.Push(context.Background(), task); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	dq.Close()
+
+	dq = openWAL(t, dir, tqwp.JSONCodec, 1, 2)
+	defer dq.Close()
+	tasks := popAll(t, dq)
+	if len(tasks) != 1 || tasks[0].Retries() != 2 {
+		t.Fatalf("replayed %d tasks, want the retried task once with 2 retries", len(tasks))
+	}
+}
@@ -0,0 +1,88 @@
+package tqwp
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrPermanent marks errors that must not be retried.
+// Use Permanent to wrap an error returned from Process.
+var ErrPermanent = errors.New("permanent error")
+
+// permanentError wraps an error returned by Permanent.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+func (e *permanentError) Is(target error) bool {
+	return target == ErrPermanent
+}
+
+// Permanent wraps err so that workers give up on the task right away instead
+// of retrying it, e.g. for a 404 response that retrying will never fix.
+// Permanent returns nil if err is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether any error in err's chain was marked with Permanent.
+func IsPermanent(err error) bool {
+	return errors.Is(err, ErrPermanent)
+}
+
+// RetryAfterError is a transient error that requests a specific delay before
+// the task is retried, overriding the delay of the pool's retry policy.
+// The retry policy still decides whether the task is retried at all.
+type RetryAfterError struct {
+	Err   error
+	Delay time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter wraps err to request that the task is retried after d,
+// e.g. when a remote endpoint answered with a Retry-After header.
+// RetryAfter returns nil if err is nil.
+func RetryAfter(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &RetryAfterError{Err: err, Delay: d}
+}
+
+// retryDecision decides whether a task that failed with err is retried and
+// after which delay, honouring Permanent and RetryAfter errors before
+// consulting the retry policy.
+func (wp *WorkerPool) retryDecision(task Task, err error, retries uint) (time.Duration, bool) {
+	if IsPermanent(err) {
+		return 0, false
+	}
+
+	delay, ok := wp.retryPolicy.Retry(task, err, retries)
+	if !ok {
+		return 0, false
+	}
+
+	var rae *RetryAfterError
+	if errors.As(err, &rae) {
+		delay = rae.Delay
+	}
+	return delay, true
+}
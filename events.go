@@ -0,0 +1,367 @@
+package tqwp
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultListenerTimeout = time.Second
+	defaultEventBufferSize = 1024
+)
+
+// EventKind identifies a lifecycle event of a task or of the pool.
+type EventKind uint8
+
+const (
+	// EventEnqueue is emitted when a task is accepted by the pool, before
+	// it reaches the queue. Retries are not enqueue events.
+	EventEnqueue EventKind = iota + 1
+
+	// EventStart is emitted when a worker starts an attempt of a task.
+	EventStart
+
+	// EventSuccess is emitted when an attempt of a task succeeds.
+	EventSuccess
+
+	// EventRetry is emitted when an attempt of a task fails and the task
+	// is retried.
+	EventRetry
+
+	// EventFailure is emitted when the pool gives up on a task.
+	EventFailure
+
+	// EventDrop is emitted when a task is discarded before it could
+	// complete: rejected because the queue is full, dropped by the overflow
+	// policy, or cancelled with the pool or its schedule.
+	EventDrop
+
+	// EventPoolStart is emitted when the pool is started.
+	EventPoolStart
+
+	// EventPoolStop is emitted when the pool is stopped, after all other
+	// events.
+	EventPoolStop
+)
+
+var eventKindNames = [...]string{
+	EventEnqueue:   "enqueue",
+	EventStart:     "start",
+	EventSuccess:   "success",
+	EventRetry:     "retry",
+	EventFailure:   "failure",
+	EventDrop:      "drop",
+	EventPoolStart: "pool start",
+	EventPoolStop:  "pool stop",
+}
+
+// String returns the name of the event kind.
+func (k EventKind) String() string {
+	if int(k) < len(eventKindNames) && eventKindNames[k] != "" {
+		return eventKindNames[k]
+	}
+	return fmt.Sprintf("EventKind(%d)", k)
+}
+
+// Event describes something that happened to a task or to the pool.
+// Fields that do not apply to the kind of event are zero.
+type Event struct {
+	Kind EventKind
+	Time time.Time
+
+	// Task is the task the event is about. It is nil for pool events.
+	Task Task
+
+	// Worker is the ID of the worker processing the task, for start,
+	// success, retry and failure events.
+	Worker int
+
+	// Attempt is the number of the attempt, starting at 1, for start,
+	// success, retry and failure events.
+	Attempt uint
+
+	// Err is the error of the failed attempt, or the reason a task was
+	// dropped.
+	Err error
+
+	// Duration is the duration of the attempt for success, retry and
+	// failure events, and the total processing time for pool stop events.
+	Duration time.Duration
+
+	// RetryIn is the delay before the task is retried, for retry events.
+	RetryIn time.Duration
+}
+
+// Listener is a function receiving lifecycle events.
+type Listener func(Event)
+
+// DeliveryMode specifies how events are delivered to a listener.
+type DeliveryMode uint8
+
+const (
+	// DeliverSync makes the pool wait until the listener has handled an
+	// event before it carries on, for example before a worker acks the
+	// task. The pool stops waiting once WorkerPoolConfig.ListenerTimeout
+	// elapses, so a slow listener cannot stall the workers. A listener
+	// that timed out is lagging: its events are then delivered without
+	// waiting until it has handled all of them, so a hung listener costs
+	// the pool a single timeout rather than one per event.
+	DeliverSync DeliveryMode = iota
+
+	// DeliverAsync hands events to the listener without waiting.
+	DeliverAsync
+)
+
+// Subscription is a listener registered on a WorkerPool.
+//
+// Each listener receives its events one at a time from a goroutine of its
+// own, in the order they were emitted, so the events of a task always
+// arrive in order. Events waiting for a listener are buffered up to
+// WorkerPoolConfig.EventBufferSize; once the buffer is full, further events
+// are discarded for that listener and counted by Dropped. A listener that
+// panics is recovered and keeps receiving events.
+type Subscription struct {
+	pool  *WorkerPool
+	fn    Listener
+	mode  DeliveryMode
+	kinds map[EventKind]bool
+
+	events  chan delivery
+	once    sync.Once
+	dropped uint64
+	lagging atomic.Bool
+}
+
+// delivery is an event waiting to be handled by a listener. For
+// synchronous delivery, done is closed once the listener returns.
+type delivery struct {
+	event Event
+	done  chan struct{}
+}
+
+// Subscribe registers l to receive the events of the given kinds, or of
+// every kind if none is given, with the given delivery mode.
+// Listeners subscribed after Stop receive no events.
+func (wp *WorkerPool) Subscribe(l Listener, mode DeliveryMode, kinds ...EventKind) *Subscription {
+	sub := &Subscription{
+		pool:   wp,
+		fn:     l,
+		mode:   mode,
+		events: make(chan delivery, wp.eventBufferSize),
+	}
+	if len(kinds) > 0 {
+		sub.kinds = make(map[EventKind]bool, len(kinds))
+		for _, k := range kinds {
+			sub.kinds[k] = true
+		}
+	}
+
+	wp.listenersMu.Lock()
+	defer wp.listenersMu.Unlock()
+	if wp.listenersClosed {
+		return sub
+	}
+	wp.listeners = append(wp.listeners, sub)
+	wp.listenerWg.Add(1)
+	go sub.run()
+	return sub
+}
+
+// OnEnqueue subscribes l to EventEnqueue.
+func (wp *WorkerPool) OnEnqueue(l Listener, mode DeliveryMode) *Subscription {
+	return wp.Subscribe(l, mode, EventEnqueue)
+}
+
+// OnStart subscribes l to EventStart.
+func (wp *WorkerPool) OnStart(l Listener, mode DeliveryMode) *Subscription {
+	return wp.Subscribe(l, mode, EventStart)
+}
+
+// OnSuccess subscribes l to EventSuccess.
+func (wp *WorkerPool) OnSuccess(l Listener, mode DeliveryMode) *Subscription {
+	return wp.Subscribe(l, mode, EventSuccess)
+}
+
+// OnRetry subscribes l to EventRetry.
+func (wp *WorkerPool) OnRetry(l Listener, mode DeliveryMode) *Subscription {
+	return wp.Subscribe(l, mode, EventRetry)
+}
+
+// OnFailure subscribes l to EventFailure.
+func (wp *WorkerPool) OnFailure(l Listener, mode DeliveryMode) *Subscription {
+	return wp.Subscribe(l, mode, EventFailure)
+}
+
+// OnDrop subscribes l to EventDrop.
+func (wp *WorkerPool) OnDrop(l Listener, mode DeliveryMode) *Subscription {
+	return wp.Subscribe(l, mode, EventDrop)
+}
+
+// OnPoolStart subscribes l to EventPoolStart.
+func (wp *WorkerPool) OnPoolStart(l Listener, mode DeliveryMode) *Subscription {
+	return wp.Subscribe(l, mode, EventPoolStart)
+}
+
+// OnPoolStop subscribes l to EventPoolStop.
+func (wp *WorkerPool) OnPoolStop(l Listener, mode DeliveryMode) *Subscription {
+	return wp.Subscribe(l, mode, EventPoolStop)
+}
+
+// Unsubscribe stops the delivery of events to the listener. Events already
+// emitted are still delivered.
+func (s *Subscription) Unsubscribe() {
+	wp := s.pool
+
+	wp.listenersMu.Lock()
+	defer wp.listenersMu.Unlock()
+	for i, sub := range wp.listeners {
+		if sub == s {
+			wp.listeners = append(wp.listeners[:i], wp.listeners[i+1:]...)
+			s.close()
+			return
+		}
+	}
+}
+
+// Dropped returns the number of events discarded because the buffer of the
+// listener was full.
+func (s *Subscription) Dropped() uint64 {
+	s.pool.listenersMu.Lock()
+	defer s.pool.listenersMu.Unlock()
+	return s.dropped
+}
+
+// close ends the delivery goroutine once the buffered events are handled.
+func (s *Subscription) close() {
+	s.once.Do(func() {
+		close(s.events)
+	})
+}
+
+// run delivers events to the listener until the subscription is closed.
+func (s *Subscription) run() {
+	defer s.pool.listenerWg.Done()
+
+	for d := range s.events {
+		s.call(d.event)
+		if d.done != nil {
+			close(d.done)
+		}
+		if len(s.events) == 0 {
+			s.lagging.Store(false)
+		}
+	}
+}
+
+// call passes event to the listener, recovering from a panic.
+func (s *Subscription) call(event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.pool.logger.Log(LevelError, "Listener panicked",
+				Field{Key: "event", Value: event.Kind.String()},
+				Field{Key: "panic", Value: r},
+			)
+		}
+	}()
+	s.fn(event)
+}
+
+// wants reports whether the listener subscribed to events of kind k.
+func (s *Subscription) wants(k EventKind) bool {
+	return s.kinds == nil || s.kinds[k]
+}
+
+// emit delivers event to the listeners subscribed to its kind.
+func (wp *WorkerPool) emit(event Event) {
+	event.Time = time.Now()
+
+	var waits []delivery
+	var subs []*Subscription
+	wp.listenersMu.Lock()
+	for _, sub := range wp.listeners {
+		if !sub.wants(event.Kind) {
+			continue
+		}
+		d := delivery{event: event}
+		if sub.mode == DeliverSync && !sub.lagging.Load() {
+			d.done = make(chan struct{})
+		}
+		select {
+		case sub.events <- d:
+			if d.done != nil {
+				waits = append(waits, d)
+				subs = append(subs, sub)
+			}
+		default:
+			sub.dropped++
+			wp.logger.Log(LevelWarn, "Listener buffer full, event discarded",
+				Field{Key: "event", Value: event.Kind.String()},
+			)
+		}
+	}
+	wp.listenersMu.Unlock()
+
+	if len(waits) == 0 {
+		return
+	}
+	timer := time.NewTimer(wp.listenerTimeout)
+	defer timer.Stop()
+	for i, d := range waits {
+		select {
+		case <-d.done:
+			continue
+		case <-timer.C:
+		}
+
+		// Stop waiting on every listener still busy with the event.
+		for j := i; j < len(waits); j++ {
+			select {
+			case <-waits[j].done:
+				continue
+			default:
+			}
+			subs[j].lagging.Store(true)
+			wp.logger.Log(LevelWarn, "Listener timed out, delivering its events without waiting until it catches up",
+				Field{Key: "event", Value: event.Kind.String()},
+				Field{Key: "timeout", Value: wp.listenerTimeout},
+			)
+		}
+		return
+	}
+}
+
+// emitTask emits an event of kind k about task.
+func (wp *WorkerPool) emitTask(k EventKind, task Task, err error) {
+	wp.emit(Event{Kind: k, Task: task, Err: err})
+}
+
+// closeListeners ends the delivery of events once the listeners handled the
+// events already emitted, waiting for them at most for the listener timeout.
+func (wp *WorkerPool) closeListeners() {
+	wp.listenersMu.Lock()
+	wp.listenersClosed = true
+	listeners := wp.listeners
+	wp.listeners = nil
+	wp.listenersMu.Unlock()
+
+	for _, sub := range listeners {
+		sub.close()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wp.listenerWg.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(wp.listenerTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		wp.logger.Log(LevelWarn, "Listeners timed out handling pending events",
+			Field{Key: "timeout", Value: wp.listenerTimeout},
+		)
+	}
+}
@@ -0,0 +1,102 @@
+package tqwp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abdullahnettoor/tqwp"
+)
+
+// TestHungSyncListener checks that a synchronous listener that never
+// returns costs the workers a single listener timeout, not one per event.
+func TestHungSyncListener(t *testing.T) {
+	const timeout = 200 * time.Millisecond
+	wp := tqwp.New(&tqwp.WorkerPoolConfig{
+		NumOfWorkers:    4,
+		MaxRetries:      3,
+		QueueSize:       10,
+		Logger:          tqwp.NopLogger{},
+		ListenerTimeout: timeout,
+	})
+
+	hang := make(chan struct{})
+	defer close(hang)
+	wp.Subscribe(func(tqwp.Event) { <-hang }, tqwp.DeliverSync)
+
+	start := time.Now()
+	wp.Start()
+	for i := 0; i < 20; i++ {
+		wp.EnqueueTask(&flakyTask{})
+	}
+	stopWithin(t, wp, time.Minute)
+
+	// Stop waits for the listener once more when closing it.
+	if elapsed := time.Since(start); elapsed > 4*timeout {
+		t.Fatalf("pool took %v with a hung listener, want about %v", elapsed, 2*timeout)
+	}
+}
+
+// funcTask runs fn.
+type funcTask struct {
+	tqwp.TaskModel
+	fn func()
+}
+
+func (t *funcTask) Process() error {
+	t.fn()
+	return nil
+}
+
+// TestSlowDropListenerDoesNotBlockWorkers checks that workers keep moving
+// spilled tasks to the queue while a synchronous listener handles the
+// rejection of a task.
+func TestSlowDropListenerDoesNotBlockWorkers(t *testing.T) {
+	wp := tqwp.New(&tqwp.WorkerPoolConfig{
+		NumOfWorkers:    1,
+		QueueSize:       1,
+		Overflow:        tqwp.OverflowSpill,
+		SpillSize:       1,
+		Logger:          tqwp.NopLogger{},
+		ListenerTimeout: time.Minute,
+	})
+
+	handling := make(chan struct{})
+	release := make(chan struct{})
+	wp.OnDrop(func(tqwp.Event) {
+		close(handling)
+		<-release
+	}, tqwp.DeliverSync)
+
+	started := make(chan struct{})
+	gate := make(chan struct{})
+	processed := make(chan struct{})
+	wp.Start()
+	wp.EnqueueTask(&funcTask{fn: func() {
+		close(started)
+		<-gate
+	}})
+	<-started
+	wp.EnqueueTask(&funcTask{fn: func() { close(processed) }})
+
+	// Fill the spill buffer until a task is rejected.
+	go func() {
+		for {
+			select {
+			case <-handling:
+				return
+			default:
+				wp.EnqueueTask(&funcTask{fn: func() {}})
+			}
+		}
+	}()
+	<-handling
+
+	close(gate)
+	select {
+	case <-processed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("worker blocked while the listener handled a rejected task")
+	}
+	close(release)
+	stopWithin(t, wp, time.Minute)
+}
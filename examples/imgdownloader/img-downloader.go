@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"time"
 
 	"github.com/abdullahnettoor/tqwp"
 )
@@ -18,8 +21,14 @@ type FileDownloadTask struct {
 	FileName string
 }
 
-// Process downloads the file from URL and saves it inside the "downloads" folder in the "imgdownloader" directory.
+// Process downloads the file without any cancellation.
 func (t *FileDownloadTask) Process() error {
+	return t.ProcessContext(context.Background())
+}
+
+// ProcessContext downloads the file from URL and saves it inside the "downloads" folder in the "imgdownloader" directory.
+// The download is aborted as soon as ctx is cancelled.
+func (t *FileDownloadTask) ProcessContext(ctx context.Context) error {
 	// Ensure the "downloads" folder exists within "imgdownloader".
 	downloadPath := filepath.Join("examples", "imgdownloader", "downloads")
 	err := os.MkdirAll(downloadPath, os.ModePerm)
@@ -28,15 +37,24 @@ func (t *FileDownloadTask) Process() error {
 	}
 
 	// Download the file.
-	resp, err := http.Get(t.URL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request for %s: %v", t.URL, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to download file from %s: %v", t.URL, err)
 	}
 	defer resp.Body.Close()
 
+	// Retrying will not help if the file does not exist.
+	if resp.StatusCode == http.StatusNotFound {
+		return tqwp.Permanent(fmt.Errorf("failed to download file from %s --- %v: File Not Found", t.URL, resp.StatusCode))
+	}
+
 	// Return error if we get status code other than 200
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download file from %s --- %v: File Not Found", t.URL, resp.StatusCode)
+		return fmt.Errorf("failed to download file from %s --- %v", t.URL, resp.StatusCode)
 	}
 
 	// Create the file inside the "downloads" folder.
@@ -117,11 +135,20 @@ func main() {
 		NumOfWorkers: numOfWorkers,
 		MaxRetries:   maxRetries,
 		QueueSize:    10,
+		TaskTimeout:  30 * time.Second,
+		Backoff: tqwp.ExponentialBackoff{
+			Initial: 500 * time.Millisecond,
+			Max:     5 * time.Second,
+		},
 	})
 	defer wp.Summary()
 	defer wp.Stop()
 
-	wp.Start()
+	// Cancel in-flight downloads when the program is interrupted.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	wp.StartContext(ctx)
 
 	// Enqueue a task for each file download.
 	for i, url := range urlList {
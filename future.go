@@ -0,0 +1,144 @@
+package tqwp
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// completer is implemented by tasks that need to learn their final outcome,
+// after all retries, from the worker pool.
+type completer interface {
+	complete(err error)
+}
+
+// completeTask reports the final outcome of task if it implements completer.
+func completeTask(task Task, err error) {
+	if c, ok := task.(completer); ok {
+		c.complete(err)
+	}
+}
+
+// Future is a handle to the result of a function submitted with Submit.
+type Future[T any] struct {
+	done     chan struct{}
+	mu       sync.Mutex
+	value    T
+	err      error
+	started  time.Time
+	duration time.Duration
+	attempts atomic.Uint32
+}
+
+// Done returns a channel that is closed once the future is resolved.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Await waits until the future is resolved or ctx is done. It returns the
+// value of the successful attempt, or the error of the final attempt once
+// the task is out of retries.
+func (f *Future[T]) Await(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Attempts returns the number of times the function has been called so far.
+func (f *Future[T]) Attempts() uint {
+	return uint(f.attempts.Load())
+}
+
+// Duration returns the time from the start of the first attempt until the
+// future was resolved. It is zero until the future is resolved.
+func (f *Future[T]) Duration() time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.duration
+}
+
+// funcTask adapts a function submitted with Submit to the Task interface.
+type funcTask[T any] struct {
+	TaskModel
+	fn     func(ctx context.Context) (T, error)
+	future *Future[T]
+}
+
+// Process implements Task.
+func (t *funcTask[T]) Process() error {
+	return t.ProcessContext(context.Background())
+}
+
+// ProcessContext implements ContextTask.
+func (t *funcTask[T]) ProcessContext(ctx context.Context) error {
+	f := t.future
+
+	f.mu.Lock()
+	if f.started.IsZero() {
+		f.started = time.Now()
+	}
+	f.mu.Unlock()
+	f.attempts.Add(1)
+
+	value, err := t.fn(ctx)
+	if err != nil {
+		return err
+	}
+
+	// An attempt abandoned after a timeout may succeed after the future
+	// was resolved; its value is discarded.
+	f.mu.Lock()
+	select {
+	case <-f.done:
+	default:
+		f.value = value
+	}
+	f.mu.Unlock()
+	return nil
+}
+
+// complete implements completer.
+func (t *funcTask[T]) complete(err error) {
+	f := t.future
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return
+	default:
+	}
+
+	if err != nil {
+		var zero T
+		f.value = zero
+		f.err = err
+	}
+	if !f.started.IsZero() {
+		f.duration = time.Since(f.started)
+	}
+	close(f.done)
+}
+
+// Submit enqueues fn on wp and returns a Future for its result. fn is retried
+// according to the pool's retry policy, and receives the pool's context
+// bounded by the task timeout. If the pool is cancelled before fn succeeds,
+// the future is resolved with the cancellation error.
+func Submit[T any](wp *WorkerPool, fn func(ctx context.Context) (T, error)) *Future[T] {
+	f := &Future[T]{
+		done: make(chan struct{}),
+	}
+	wp.EnqueueTask(&funcTask[T]{
+		fn:     fn,
+		future: f,
+	})
+	return f
+}
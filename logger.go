@@ -0,0 +1,123 @@
+package tqwp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"time"
+)
+
+// LogLevel is the severity of a log message. Its values match the levels
+// of log/slog.
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = LogLevel(slog.LevelDebug)
+	LevelInfo  LogLevel = LogLevel(slog.LevelInfo)
+	LevelWarn  LogLevel = LogLevel(slog.LevelWarn)
+	LevelError LogLevel = LogLevel(slog.LevelError)
+)
+
+// String returns the name of the level.
+func (l LogLevel) String() string {
+	return slog.Level(l).String()
+}
+
+// Field is a key-value pair attached to a log message.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Logger is the interface a WorkerPool writes its log messages to.
+// Implementations must be safe for concurrent use.
+//
+// Messages about a task carry structured fields, such as "worker",
+// "task", "attempt", "error" and "duration".
+type Logger interface {
+	Log(level LogLevel, msg string, fields ...Field)
+}
+
+// SlogLogger is a Logger writing to a *slog.Logger.
+type SlogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger writing to l.
+func NewSlogLogger(l *slog.Logger) *SlogLogger {
+	return &SlogLogger{logger: l}
+}
+
+// Log implements Logger.
+func (l *SlogLogger) Log(level LogLevel, msg string, fields ...Field) {
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.Any(f.Key, f.Value)
+	}
+	l.logger.LogAttrs(context.Background(), slog.Level(level), msg, attrs...)
+}
+
+// defaultLogger returns the logger used when WorkerPoolConfig.Logger is
+// not set: text records written to stdout. Filtering is left to the pool.
+func defaultLogger() Logger {
+	handler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	})
+	return NewSlogLogger(slog.New(handler))
+}
+
+// NopLogger is a Logger discarding every message.
+type NopLogger struct{}
+
+// Log implements Logger.
+func (NopLogger) Log(level LogLevel, msg string, fields ...Field) {}
+
+// levelLogger drops the messages below a minimum level.
+type levelLogger struct {
+	logger Logger
+	min    LogLevel
+}
+
+// Log implements Logger.
+func (l levelLogger) Log(level LogLevel, msg string, fields ...Field) {
+	if level < l.min {
+		return
+	}
+	l.logger.Log(level, msg, fields...)
+}
+
+// IdentifiableTask is an optional interface for tasks that have an ID,
+// which is then used to refer to the task in log messages.
+type IdentifiableTask interface {
+	Task
+
+	// TaskID returns the ID of the task.
+	TaskID() string
+}
+
+// taskID returns the ID used to refer to task in log messages. Tasks
+// without an ID are referred to by type and, for pointers, address.
+func taskID(task Task) string {
+	if it, ok := task.(IdentifiableTask); ok {
+		return it.TaskID()
+	}
+	if reflect.ValueOf(task).Kind() == reflect.Pointer {
+		return fmt.Sprintf("%T@%p", task, task)
+	}
+	return fmt.Sprintf("%T", task)
+}
+
+// taskFields returns the fields describing an attempt of task by worker.
+func taskFields(worker int, task Task, attempt uint, err error, duration time.Duration) []Field {
+	fields := []Field{
+		{Key: "worker", Value: worker},
+		{Key: "task", Value: taskID(task)},
+		{Key: "attempt", Value: attempt},
+	}
+	if err != nil {
+		fields = append(fields, Field{Key: "error", Value: err})
+	}
+	return append(fields, Field{Key: "duration", Value: duration})
+}
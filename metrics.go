@@ -0,0 +1,244 @@
+package tqwp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MetricsSink receives the metrics of a WorkerPool as they change.
+// Implementations must be safe for concurrent use and should return
+// quickly, since most methods are called from the workers.
+type MetricsSink interface {
+	// TaskEnqueued is called when a task is accepted by the pool,
+	// excluding retries.
+	TaskEnqueued()
+
+	// TaskStarted is called when a worker starts an attempt of a task.
+	TaskStarted()
+
+	// TaskSucceeded is called when an attempt of a task succeeds, with the
+	// duration of the attempt.
+	TaskSucceeded(d time.Duration)
+
+	// TaskRetried is called when an attempt of a task fails and the task
+	// is retried, with the duration of the attempt.
+	TaskRetried(d time.Duration)
+
+	// TaskFailed is called when the last attempt of a task fails and the
+	// pool gives up on it, with the duration of the attempt.
+	TaskFailed(d time.Duration)
+
+	// QueueDepth is called with the number of tasks waiting in the queue
+	// whenever it may have changed.
+	QueueDepth(n int)
+
+	// BusyWorkers is called with the number of workers processing a task
+	// whenever it changes.
+	BusyWorkers(n int)
+}
+
+// nopMetrics is the MetricsSink used when none is configured.
+type nopMetrics struct{}
+
+func (nopMetrics) TaskEnqueued()                 {}
+func (nopMetrics) TaskStarted()                  {}
+func (nopMetrics) TaskSucceeded(d time.Duration) {}
+func (nopMetrics) TaskRetried(d time.Duration)   {}
+func (nopMetrics) TaskFailed(d time.Duration)    {}
+func (nopMetrics) QueueDepth(n int)              {}
+func (nopMetrics) BusyWorkers(n int)             {}
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the task
+// latency histogram of a PrometheusExporter created without buckets.
+var DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// PrometheusExporter is a MetricsSink that keeps the metrics of a pool and
+// serves them in the Prometheus text exposition format. It implements
+// http.Handler, so it can be mounted directly on a server:
+//
+//	metrics := tqwp.NewPrometheusExporter("tqwp", nil)
+//	http.Handle("/metrics", metrics)
+type PrometheusExporter struct {
+	namespace string
+	buckets   []float64
+
+	enqueued    atomic.Uint64
+	started     atomic.Uint64
+	succeeded   atomic.Uint64
+	retried     atomic.Uint64
+	failed      atomic.Uint64
+	queueDepth  atomic.Int64
+	busyWorkers atomic.Int64
+
+	mu     sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewPrometheusExporter returns a PrometheusExporter naming its metrics
+// with the given namespace prefix, and recording the duration of task
+// attempts in a histogram with the given bucket upper bounds in seconds,
+// sorted in ascending order. Nil buckets default to DefaultLatencyBuckets.
+func NewPrometheusExporter(namespace string, buckets []float64) *PrometheusExporter {
+	if buckets == nil {
+		buckets = DefaultLatencyBuckets
+	}
+	return &PrometheusExporter{
+		namespace: namespace,
+		buckets:   buckets,
+		counts:    make([]uint64, len(buckets)),
+	}
+}
+
+// TaskEnqueued implements MetricsSink.
+func (e *PrometheusExporter) TaskEnqueued() {
+	e.enqueued.Add(1)
+}
+
+// TaskStarted implements MetricsSink.
+func (e *PrometheusExporter) TaskStarted() {
+	e.started.Add(1)
+}
+
+// TaskSucceeded implements MetricsSink.
+func (e *PrometheusExporter) TaskSucceeded(d time.Duration) {
+	e.succeeded.Add(1)
+	e.observe(d)
+}
+
+// TaskRetried implements MetricsSink.
+func (e *PrometheusExporter) TaskRetried(d time.Duration) {
+	e.retried.Add(1)
+	e.observe(d)
+}
+
+// TaskFailed implements MetricsSink.
+func (e *PrometheusExporter) TaskFailed(d time.Duration) {
+	e.failed.Add(1)
+	e.observe(d)
+}
+
+// QueueDepth implements MetricsSink.
+func (e *PrometheusExporter) QueueDepth(n int) {
+	e.queueDepth.Store(int64(n))
+}
+
+// BusyWorkers implements MetricsSink.
+func (e *PrometheusExporter) BusyWorkers(n int) {
+	e.busyWorkers.Store(int64(n))
+}
+
+// observe records the duration of a task attempt in the latency histogram.
+func (e *PrometheusExporter) observe(d time.Duration) {
+	seconds := d.Seconds()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i, upper := range e.buckets {
+		if seconds <= upper {
+			e.counts[i]++
+			break
+		}
+	}
+	e.sum += seconds
+	e.count++
+}
+
+// name returns the full name of a metric.
+func (e *PrometheusExporter) name(metric string) string {
+	if e.namespace == "" {
+		return metric
+	}
+	return e.namespace + "_" + metric
+}
+
+// WriteTo writes the metrics to w in the Prometheus text exposition format.
+func (e *PrometheusExporter) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+
+	counter := func(metric, help string, v uint64) {
+		name := e.name(metric)
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, v)
+	}
+	gauge := func(metric, help string, v int64) {
+		name := e.name(metric)
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", name, help, name, name, v)
+	}
+
+	counter("tasks_enqueued_total", "Number of tasks enqueued, excluding retries.", e.enqueued.Load())
+	counter("tasks_started_total", "Number of task attempts started.", e.started.Load())
+	counter("tasks_succeeded_total", "Number of tasks that succeeded.", e.succeeded.Load())
+	counter("tasks_retried_total", "Number of failed task attempts that were retried.", e.retried.Load())
+	counter("tasks_failed_total", "Number of tasks that failed after their last attempt.", e.failed.Load())
+	gauge("queue_depth", "Number of tasks waiting in the queue.", e.queueDepth.Load())
+	gauge("busy_workers", "Number of workers processing a task.", e.busyWorkers.Load())
+
+	e.mu.Lock()
+	counts := append([]uint64(nil), e.counts...)
+	sum, count := e.sum, e.count
+	e.mu.Unlock()
+
+	name := e.name("task_duration_seconds")
+	fmt.Fprintf(cw, "# HELP %s Duration of task attempts.\n# TYPE %s histogram\n", name, name)
+	var cumulative uint64
+	for i, upper := range e.buckets {
+		cumulative += counts[i]
+		fmt.Fprintf(cw, "%s_bucket{le=\"%s\"} %d\n", name, formatFloat(upper), cumulative)
+	}
+	fmt.Fprintf(cw, "%s_bucket{le=\"+Inf\"} %d\n", name, count)
+	fmt.Fprintf(cw, "%s_sum %s\n", name, formatFloat(sum))
+	fmt.Fprintf(cw, "%s_count %d\n", name, count)
+
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, cw.w.Flush()
+}
+
+// ServeHTTP implements http.Handler.
+func (e *PrometheusExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	e.WriteTo(w)
+}
+
+// formatFloat formats v as a Prometheus sample value.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// countingWriter counts the bytes written through it and remembers the
+// first error.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
+
+// taskEnqueued reports a task accepted by the pool to the metrics sink.
+func (wp *WorkerPool) taskEnqueued() {
+	wp.metrics.TaskEnqueued()
+	wp.reportQueueDepth()
+}
+
+// reportQueueDepth reports the current queue depth to the metrics sink.
+func (wp *WorkerPool) reportQueueDepth() {
+	wp.metrics.QueueDepth(wp.queue.Len())
+}
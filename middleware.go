@@ -0,0 +1,111 @@
+package tqwp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Handler runs a single attempt of a task. The innermost Handler of a pool
+// calls the task's ProcessContext or Process method.
+type Handler func(ctx context.Context, task Task) error
+
+// Middleware wraps a Handler with cross-cutting logic, such as timing,
+// logging or refreshing credentials, and returns the wrapped Handler.
+// A middleware may act before and after calling next, change the context
+// or the error, or skip next altogether, for example to rate limit tasks.
+type Middleware func(next Handler) Handler
+
+// chainMiddleware wraps h with mws, so that mws[0] is the outermost.
+func chainMiddleware(h Handler, mws []Middleware) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// processAttempt runs an attempt of task through the middleware chain of
+// the pool. A panic raised by a middleware is recovered like a panic of
+// the task and returned as a *PanicError.
+func (wp *WorkerPool) processAttempt(ctx context.Context, task Task) (err error) {
+	defer recoverPanic(&err)
+	return wp.handler(ctx, task)
+}
+
+// TimingMiddleware returns a Middleware calling report with the duration
+// and the error of every attempt.
+func TimingMiddleware(report func(task Task, d time.Duration, err error)) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, task Task) error {
+			start := time.Now()
+			err := next(ctx, task)
+			report(task, time.Since(start), err)
+			return err
+		}
+	}
+}
+
+// RecoveryMiddleware returns a Middleware converting a panic raised further
+// down the chain into a *PanicError. Workers recover panics anyway; placing
+// it in the chain lets the middlewares in front of it see the panic as an
+// error.
+func RecoveryMiddleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, task Task) (err error) {
+			defer recoverPanic(&err)
+			return next(ctx, task)
+		}
+	}
+}
+
+// TimeoutMiddleware returns a Middleware bounding the context of every
+// attempt to d. Unlike WorkerPoolConfig.TaskTimeout it is cooperative: the
+// attempt is only interrupted if the task honours its context. An attempt
+// that fails after its deadline returns an error wrapping ErrTaskTimeout.
+func TimeoutMiddleware(d time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, task Task) error {
+			tctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+
+			err := next(tctx, task)
+			if err != nil && errors.Is(tctx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+				return fmt.Errorf("%w after %v: %v", ErrTaskTimeout, d, err)
+			}
+			return err
+		}
+	}
+}
+
+// LoggingMiddleware returns a Middleware logging the start of every attempt
+// at LevelDebug and its outcome at the given level, with the task, attempt,
+// error and duration as fields.
+func LoggingMiddleware(logger Logger, level LogLevel) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, task Task) error {
+			attempt := uint(1)
+			if rt, ok := task.(RetryableTask); ok {
+				attempt = rt.Retries() + 1
+			}
+			logger.Log(LevelDebug, "Attempt started",
+				Field{Key: "task", Value: taskID(task)},
+				Field{Key: "attempt", Value: attempt},
+			)
+
+			start := time.Now()
+			err := next(ctx, task)
+
+			fields := []Field{
+				{Key: "task", Value: taskID(task)},
+				{Key: "attempt", Value: attempt},
+			}
+			if err != nil {
+				fields = append(fields, Field{Key: "error", Value: err})
+			}
+			fields = append(fields, Field{Key: "duration", Value: time.Since(start)})
+			logger.Log(level, "Attempt finished", fields...)
+			return err
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package tqwp
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is the error reported for a task attempt that panicked.
+// Workers recover the panic and handle it like any other failure, so a
+// panicking task is retried and eventually counted in TaskFailure.
+type PanicError struct {
+	// Value is the value the task passed to panic.
+	Value any
+
+	// Stack is the stack trace of the goroutine at the time of the panic.
+	Stack []byte
+}
+
+// Error implements the error interface.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error, so errors.Is and
+// errors.As can look through the panic.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// PanicHandler is called by a worker after it recovered a panic from task.
+type PanicHandler func(task Task, err *PanicError)
+
+// recoverPanic converts a recovered panic into a PanicError stored in err.
+// It must be deferred directly by the function running the task.
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = &PanicError{
+			Value: r,
+			Stack: debug.Stack(),
+		}
+	}
+}
+
+// handlePanic invokes the configured panic handler for task, if any.
+// A panic raised by the handler itself is logged and otherwise ignored.
+func (wp *WorkerPool) handlePanic(task Task, perr *PanicError) {
+	if wp.panicHandler == nil {
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			wp.logger.Log(LevelError, "Panic handler panicked", Field{Key: "panic", Value: r})
+		}
+	}()
+	wp.panicHandler(task, perr)
+}
@@ -0,0 +1,237 @@
+package tqwp
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// PriorityTask is an optional interface for tasks that declare a priority.
+// Tasks with a higher priority are processed first when the pool uses a
+// PriorityQueue. Tasks not implementing it have priority 0.
+type PriorityTask interface {
+	Task
+
+	// Priority returns the priority of the task.
+	Priority() int
+}
+
+// taskPriority returns the priority of task.
+func taskPriority(task Task) int {
+	if pt, ok := task.(PriorityTask); ok {
+		return pt.Priority()
+	}
+	return 0
+}
+
+// priorityItem is a task waiting in a PriorityTaskQueue.
+type priorityItem struct {
+	task  Task
+	score int64
+	seq   uint64
+}
+
+// priorityHeap orders items by ascending score, then by insertion order.
+type priorityHeap []*priorityItem
+
+func (h priorityHeap) Len() int { return len(h) }
+
+func (h priorityHeap) Less(i, j int) bool {
+	if h[i].score != h[j].score {
+		return h[i].score < h[j].score
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h priorityHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *priorityHeap) Push(x any) { *h = append(*h, x.(*priorityItem)) }
+
+func (h *priorityHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return item
+}
+
+// PriorityTaskQueue is a bounded QueueBackend that hands out the task with the
+// highest priority first, and tasks of equal priority in FIFO order.
+//
+// To prevent starvation of low-priority tasks, waiting tasks age: every
+// aging interval a task spends in the queue raises its effective priority
+// by one. An aging interval of zero disables aging.
+type PriorityTaskQueue struct {
+	mu     sync.Mutex
+	items  priorityHeap
+	size   int
+	aging  time.Duration
+	seq    uint64
+	closed bool
+	wake   chan struct{}
+}
+
+// NewPriorityTaskQueue returns a PriorityTaskQueue holding up to size tasks,
+// aging waiting tasks by one priority level per aging interval.
+// A size of zero is treated as one.
+func NewPriorityTaskQueue(size uint, aging time.Duration) *PriorityTaskQueue {
+	if size == 0 {
+		size = 1
+	}
+	return &PriorityTaskQueue{
+		size:  int(size),
+		aging: aging,
+		wake:  make(chan struct{}),
+	}
+}
+
+// score computes the heap key of task; lower scores are dequeued first.
+//
+// With aging, the effective priority of a task at time now is
+// priority + (now - enqueued) / aging. Comparing two tasks, now cancels out,
+// so ordering by enqueued - priority*aging gives the same order at any time
+// without re-sorting the heap.
+func (pq *PriorityTaskQueue) score(task Task) int64 {
+	priority := int64(taskPriority(task))
+	if pq.aging <= 0 {
+		return -priority
+	}
+	return time.Now().UnixNano() - priority*int64(pq.aging)
+}
+
+// broadcast wakes up every goroutine waiting for the queue to change.
+// It must be called with pq.mu held.
+func (pq *PriorityTaskQueue) broadcast() {
+	close(pq.wake)
+	pq.wake = make(chan struct{})
+}
+
+// Enqueue adds a task to the queue, blocking while the queue is full.
+func (pq *PriorityTaskQueue) Enqueue(task Task) {
+	pq.Push(context.Background(), task)
+}
+
+// Push implements QueueBackend.
+func (pq *PriorityTaskQueue) Push(ctx context.Context, task Task) error {
+	pq.mu.Lock()
+	for !pq.closed && len(pq.items) >= pq.size {
+		wake := pq.wake
+		pq.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		pq.mu.Lock()
+	}
+	defer pq.mu.Unlock()
+
+	if pq.closed {
+		return ErrQueueClosed
+	}
+	pq.push(task)
+	return nil
+}
+
+// TryPush implements TryPusher.
+func (pq *PriorityTaskQueue) TryPush(task Task) error {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	if pq.closed {
+		return ErrQueueClosed
+	}
+	if len(pq.items) >= pq.size {
+		return ErrQueueFull
+	}
+	pq.push(task)
+	return nil
+}
+
+// push adds task to the heap. It must be called with pq.mu held.
+func (pq *PriorityTaskQueue) push(task Task) {
+	pq.seq++
+	heap.Push(&pq.items, &priorityItem{
+		task:  task,
+		score: pq.score(task),
+		seq:   pq.seq,
+	})
+	pq.broadcast()
+}
+
+// EvictOldest implements Evicter. It removes the task that was pushed
+// first, regardless of its priority.
+func (pq *PriorityTaskQueue) EvictOldest() (Task, bool) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	if len(pq.items) == 0 {
+		return nil, false
+	}
+	oldest := 0
+	for i, item := range pq.items {
+		if item.seq < pq.items[oldest].seq {
+			oldest = i
+		}
+	}
+	item := heap.Remove(&pq.items, oldest).(*priorityItem)
+	pq.broadcast()
+	return item.task, true
+}
+
+// Pop implements QueueBackend.
+func (pq *PriorityTaskQueue) Pop(ctx context.Context) (Task, error) {
+	pq.mu.Lock()
+	for len(pq.items) == 0 {
+		if pq.closed {
+			pq.mu.Unlock()
+			return nil, ErrQueueClosed
+		}
+		wake := pq.wake
+		pq.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		pq.mu.Lock()
+	}
+	defer pq.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	item := heap.Pop(&pq.items).(*priorityItem)
+	pq.broadcast()
+	return item.task, nil
+}
+
+// Ack implements QueueBackend. It is a no-op.
+func (pq *PriorityTaskQueue) Ack(task Task) error {
+	return nil
+}
+
+// Nack implements QueueBackend. It is a no-op.
+func (pq *PriorityTaskQueue) Nack(task Task, err error) error {
+	return nil
+}
+
+// Len implements QueueBackend.
+func (pq *PriorityTaskQueue) Len() int {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	return len(pq.items)
+}
+
+// Close implements QueueBackend.
+func (pq *PriorityTaskQueue) Close() error {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	pq.closed = true
+	pq.broadcast()
+	return nil
+}
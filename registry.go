@@ -0,0 +1,140 @@
+package tqwp
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrUnregisteredTask is returned when encoding or decoding a task whose
+// type was not registered with the Registry.
+var ErrUnregisteredTask = errors.New("task type not registered")
+
+// Registry maps task types to names, so tasks can be persisted and turned
+// back into runnable tasks later, possibly by another process.
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]func() Task
+	names     map[reflect.Type]string
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: make(map[string]func() Task),
+		names:     make(map[reflect.Type]string),
+	}
+}
+
+// DefaultRegistry is the registry used by RegisterTask.
+var DefaultRegistry = NewRegistry()
+
+// RegisterTask registers a task type with DefaultRegistry.
+func RegisterTask(name string, factory func() Task) {
+	DefaultRegistry.Register(name, factory)
+}
+
+// Register registers the type of the tasks returned by factory under name.
+// factory must return a new, empty task each time it is called, usually a
+// pointer to a struct, e.g. func() tqwp.Task { return &EmailTask{} }.
+// Register panics if name or the task type is already registered.
+func (r *Registry) Register(name string, factory func() Task) {
+	typ := reflect.TypeOf(factory())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.factories[name]; ok {
+		panic(fmt.Sprintf("tqwp: task name %q registered twice", name))
+	}
+	if other, ok := r.names[typ]; ok {
+		panic(fmt.Sprintf("tqwp: task type %v already registered as %q", typ, other))
+	}
+	r.factories[name] = factory
+	r.names[typ] = name
+}
+
+// Name returns the name task's type was registered under.
+func (r *Registry) Name(task Task) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	name, ok := r.names[reflect.TypeOf(task)]
+	if !ok {
+		return "", fmt.Errorf("%w: %T", ErrUnregisteredTask, task)
+	}
+	return name, nil
+}
+
+// New returns a new, empty task of the type registered under name.
+func (r *Registry) New(name string) (Task, error) {
+	r.mu.RLock()
+	factory, ok := r.factories[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnregisteredTask, name)
+	}
+	return factory(), nil
+}
+
+// Seal encodes task with codec into an Envelope, along with the registered
+// name of its type, the retry count of a RetryableTask and the span context
+// of a TraceableTask.
+func (r *Registry) Seal(codec Codec, task Task) (*Envelope, error) {
+	name, err := r.Name(task)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := codec.Marshal(task)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode task %q: %w", name, err)
+	}
+
+	env := &Envelope{Type: name, Payload: payload}
+	if rt, ok := task.(RetryableTask); ok {
+		env.Retries = rt.Retries()
+	}
+	if tt, ok := task.(TraceableTask); ok {
+		env.Trace = tt.TraceContext()
+	}
+	return env, nil
+}
+
+// Open decodes the task sealed in env with codec and restores its retry
+// count and span context.
+func (r *Registry) Open(codec Codec, env *Envelope) (Task, error) {
+	task, err := r.New(env.Type)
+	if err != nil {
+		return nil, err
+	}
+	if err := codec.Unmarshal(env.Payload, task); err != nil {
+		return nil, fmt.Errorf("failed to decode task %q: %w", env.Type, err)
+	}
+	if rt, ok := task.(RetryableTask); ok {
+		rt.SetRetries(env.Retries)
+	}
+	if tt, ok := task.(TraceableTask); ok {
+		tt.SetTraceContext(env.Trace)
+	}
+	return task, nil
+}
+
+// Marshal seals task into an Envelope and encodes the envelope with codec.
+func (r *Registry) Marshal(codec Codec, task Task) ([]byte, error) {
+	env, err := r.Seal(codec, task)
+	if err != nil {
+		return nil, err
+	}
+	return codec.Marshal(env)
+}
+
+// Unmarshal decodes a task encoded by Marshal with the same codec.
+func (r *Registry) Unmarshal(codec Codec, data []byte) (Task, error) {
+	var env Envelope
+	if err := codec.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("failed to decode envelope: %w", err)
+	}
+	return r.Open(codec, &env)
+}
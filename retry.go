@@ -0,0 +1,54 @@
+package tqwp
+
+import "time"
+
+// RetryableTask is implemented by tasks that keep track of their own retry
+// attempts. Only tasks implementing it are retried after a failure.
+// Embedding TaskModel is the easiest way to implement it, but tasks can also
+// keep the count elsewhere, for example in a database row.
+type RetryableTask interface {
+	Task
+
+	// Retries returns the number of retries attempted so far.
+	Retries() uint
+
+	// SetRetries records the number of retries attempted so far.
+	// It is called by the worker right before a retry is scheduled.
+	SetRetries(n uint)
+}
+
+// RetryPolicy decides whether, and when, a failed task is retried.
+type RetryPolicy interface {
+	// Retry is called after task failed with err, having already been
+	// retried the given number of times. It reports whether the task should
+	// be retried and how long to wait before the retry.
+	Retry(task Task, err error, retries uint) (delay time.Duration, ok bool)
+}
+
+// RetryPolicyFunc is an adapter to allow the use of ordinary functions as
+// a RetryPolicy.
+type RetryPolicyFunc func(task Task, err error, retries uint) (time.Duration, bool)
+
+// Retry calls f(task, err, retries).
+func (f RetryPolicyFunc) Retry(task Task, err error, retries uint) (time.Duration, bool) {
+	return f(task, err, retries)
+}
+
+// DefaultRetryPolicy retries any error up to MaxRetries times and waits
+// between attempts as decided by Backoff. A nil Backoff retries immediately.
+// It is the policy used when WorkerPoolConfig.RetryPolicy is not set.
+type DefaultRetryPolicy struct {
+	MaxRetries uint
+	Backoff    BackoffStrategy
+}
+
+// Retry implements RetryPolicy.
+func (p DefaultRetryPolicy) Retry(task Task, err error, retries uint) (time.Duration, bool) {
+	if retries >= p.MaxRetries {
+		return 0, false
+	}
+	if p.Backoff == nil {
+		return 0, true
+	}
+	return p.Backoff.Delay(retries + 1), true
+}
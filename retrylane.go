@@ -0,0 +1,184 @@
+package tqwp
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// retryLane holds retried tasks and due scheduled tasks until the queue has
+// room for them.
+//
+// Pushing such tasks straight into a bounded queue can deadlock the pool:
+// when the queue is full and every worker is retrying a task at the same
+// time, all workers block on the push and nobody is left to make room.
+// Instead, tasks wait in the lane, which is never full. Workers move them
+// into the queue each time they pop a task, and the pool's feeder goroutine
+// pushes them while waiting for room, so they also reach queues that only
+// accept a task once a worker waits for it, such as an unbuffered one.
+type retryLane struct {
+	mu    sync.Mutex
+	tasks []Task
+}
+
+// refusal is a task the queue refused with err while a lock was held. It is
+// abandoned with cancelRefused once the lock is released, so listeners are
+// never called with the lock held.
+type refusal struct {
+	task Task
+	err  error
+}
+
+// cancelRefused abandons the refused tasks.
+func (wp *WorkerPool) cancelRefused(refused []refusal) {
+	for _, r := range refused {
+		wp.cancelTask(r.task, r.err)
+		wp.taskWg.Done()
+	}
+}
+
+// requeue sends a retried or scheduled task to the queue through the retry
+// lane, without ever blocking the caller.
+func (wp *WorkerPool) requeue(task Task) {
+	wp.cancelRefused(wp.pushRetry(task))
+}
+
+// pushRetry is like requeue, but returns the tasks the queue refused instead
+// of abandoning them.
+func (wp *WorkerPool) pushRetry(task Task) []refusal {
+	wp.retries.mu.Lock()
+	defer wp.retries.mu.Unlock()
+
+	wp.retries.tasks = append(wp.retries.tasks, task)
+	refused := wp.drainRetriesLocked()
+	if len(wp.retries.tasks) > 0 {
+		wp.wakeFeeder()
+	}
+	return refused
+}
+
+// drainRetries moves tasks from the retry lane to the queue while it has room.
+func (wp *WorkerPool) drainRetries() {
+	wp.retries.mu.Lock()
+	refused := wp.drainRetriesLocked()
+	wp.retries.mu.Unlock()
+
+	wp.cancelRefused(refused)
+}
+
+// drainRetriesLocked is like drainRetries, but must be called with
+// wp.retries.mu held. Tasks the queue refuses for any other reason than
+// being full, for example because it was closed, are removed from the lane
+// and returned, for the caller to abandon once it released the lock.
+func (wp *WorkerPool) drainRetriesLocked() (refused []refusal) {
+	lane := &wp.retries
+	for len(lane.tasks) > 0 {
+		task := lane.tasks[0]
+		err := wp.tryPush(task)
+		if errors.Is(err, ErrQueueFull) {
+			return refused
+		}
+
+		lane.tasks[0] = nil
+		lane.tasks = lane.tasks[1:]
+		if err != nil {
+			refused = append(refused, refusal{task: task, err: err})
+		}
+	}
+	return refused
+}
+
+// discardRetries abandons the tasks left in the retry lane when the pool
+// is stopped.
+func (wp *WorkerPool) discardRetries(err error) {
+	wp.retries.mu.Lock()
+	tasks := wp.retries.tasks
+	wp.retries.tasks = nil
+	wp.retries.mu.Unlock()
+
+	for _, task := range tasks {
+		wp.cancelTask(task, err)
+		wp.taskWg.Done()
+	}
+}
+
+// wakeFeeder tells the feeder that tasks are waiting in the retry lane or
+// in the overflow buffer.
+func (wp *WorkerPool) wakeFeeder() {
+	select {
+	case wp.feederWake <- struct{}{}:
+	default:
+	}
+}
+
+// feed runs until ctx is done, pushing the tasks waiting in the retry lane,
+// then those in the overflow buffer, into the queue. Unlike the workers, it
+// waits for room in the queue, so waiting tasks are never stranded when no
+// worker is about to pop a task.
+func (wp *WorkerPool) feed(ctx context.Context) {
+	defer wp.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-wp.feederWake:
+		}
+
+		for {
+			task, spilled, ok := wp.nextWaitingTask()
+			if !ok {
+				break
+			}
+			err := wp.queue.Push(ctx, task)
+			if err == nil {
+				continue
+			}
+			if ctx.Err() != nil {
+				// Leave the task to be discarded by Stop.
+				wp.putBackWaitingTask(task, spilled)
+				return
+			}
+			wp.cancelTask(task, err)
+			wp.taskWg.Done()
+		}
+	}
+}
+
+// nextWaitingTask removes the first task from the retry lane or, if it is
+// empty, from the overflow buffer. spilled reports which one it came from.
+func (wp *WorkerPool) nextWaitingTask() (task Task, spilled bool, ok bool) {
+	wp.retries.mu.Lock()
+	if len(wp.retries.tasks) > 0 {
+		task = wp.retries.tasks[0]
+		wp.retries.tasks[0] = nil
+		wp.retries.tasks = wp.retries.tasks[1:]
+		wp.retries.mu.Unlock()
+		return task, false, true
+	}
+	wp.retries.mu.Unlock()
+
+	wp.spillMu.Lock()
+	defer wp.spillMu.Unlock()
+	if len(wp.spill) > 0 {
+		task = wp.spill[0]
+		wp.spill[0] = nil
+		wp.spill = wp.spill[1:]
+		return task, true, true
+	}
+	return nil, false, false
+}
+
+// putBackWaitingTask returns a task taken by nextWaitingTask to the front
+// of the retry lane or of the overflow buffer.
+func (wp *WorkerPool) putBackWaitingTask(task Task, spilled bool) {
+	if spilled {
+		wp.spillMu.Lock()
+		wp.spill = append([]Task{task}, wp.spill...)
+		wp.spillMu.Unlock()
+		return
+	}
+	wp.retries.mu.Lock()
+	wp.retries.tasks = append([]Task{task}, wp.retries.tasks...)
+	wp.retries.mu.Unlock()
+}
@@ -0,0 +1,141 @@
+package tqwp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// workerHandle is a running worker of a WorkerPool.
+type workerHandle struct {
+	id     int
+	cancel context.CancelFunc
+}
+
+// SetWorkers grows or shrinks the pool to n workers.
+// It can be called before Start, or while the pool is running.
+//
+// Shrinking is graceful: removed workers stop pulling tasks from the queue,
+// but finish the task they are processing first. A pool without workers
+// keeps its queued tasks until workers are added again.
+func (wp *WorkerPool) SetWorkers(n uint) {
+	wp.workersMu.Lock()
+	defer wp.workersMu.Unlock()
+
+	if n > wp.numOfWorkers {
+		wp.addWorkersLocked(n - wp.numOfWorkers)
+	} else {
+		wp.removeWorkersLocked(wp.numOfWorkers - n)
+	}
+}
+
+// AddWorkers adds n workers to the pool.
+func (wp *WorkerPool) AddWorkers(n uint) {
+	wp.workersMu.Lock()
+	defer wp.workersMu.Unlock()
+	wp.addWorkersLocked(n)
+}
+
+// RemoveWorkers gracefully removes up to n workers from the pool, starting
+// with the most recently added ones. See SetWorkers.
+func (wp *WorkerPool) RemoveWorkers(n uint) {
+	wp.workersMu.Lock()
+	defer wp.workersMu.Unlock()
+	wp.removeWorkersLocked(n)
+}
+
+// NumWorkers returns the number of workers of the pool.
+func (wp *WorkerPool) NumWorkers() uint {
+	wp.workersMu.Lock()
+	defer wp.workersMu.Unlock()
+	return wp.numOfWorkers
+}
+
+// WorkerIDs returns the IDs of the running workers in ascending order.
+// Worker IDs start at 1 and are never reused, so workers added after
+// others were removed get new IDs.
+func (wp *WorkerPool) WorkerIDs() []int {
+	wp.workersMu.Lock()
+	defer wp.workersMu.Unlock()
+
+	ids := make([]int, 0, len(wp.workers))
+	for _, w := range wp.workers {
+		ids = append(ids, w.id)
+	}
+	return ids
+}
+
+// addWorkersLocked must be called with wp.workersMu held.
+func (wp *WorkerPool) addWorkersLocked(n uint) {
+	if wp.stopped {
+		return
+	}
+	wp.numOfWorkers += n
+	if !wp.started {
+		return
+	}
+	for i := uint(0); i < n; i++ {
+		wp.spawnWorkerLocked()
+	}
+	wp.logger.Log(LevelInfo, "Added workers",
+		Field{Key: "added", Value: n},
+		Field{Key: "workers", Value: wp.numOfWorkers},
+	)
+}
+
+// removeWorkersLocked must be called with wp.workersMu held.
+func (wp *WorkerPool) removeWorkersLocked(n uint) {
+	if wp.stopped {
+		return
+	}
+	n = min(n, wp.numOfWorkers)
+	wp.numOfWorkers -= n
+	if !wp.started {
+		return
+	}
+
+	// Workers are appended in ID order, so the newest are at the end.
+	for _, w := range wp.workers[len(wp.workers)-int(n):] {
+		w.cancel()
+	}
+	wp.workers = wp.workers[:len(wp.workers)-int(n)]
+	wp.logger.Log(LevelInfo, "Removed workers",
+		Field{Key: "removed", Value: n},
+		Field{Key: "workers", Value: wp.numOfWorkers},
+	)
+}
+
+// spawnWorkerLocked starts a new worker with the next worker ID.
+// It must be called with wp.workersMu held.
+func (wp *WorkerPool) spawnWorkerLocked() {
+	wp.lastWorkerID++
+	ctx, cancel := context.WithCancel(wp.ctx)
+	wp.workers = append(wp.workers, &workerHandle{
+		id:     wp.lastWorkerID,
+		cancel: cancel,
+	})
+
+	wp.wg.Add(1)
+	go wp.worker(ctx, wp.lastWorkerID)
+}
+
+// formatWorkerIDs formats sorted worker IDs compactly, e.g. "1-4, 7, 9-10".
+func formatWorkerIDs(ids []int) string {
+	if len(ids) == 0 {
+		return "none"
+	}
+	var parts []string
+	for i := 0; i < len(ids); {
+		j := i
+		for j+1 < len(ids) && ids[j+1] == ids[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, fmt.Sprint(ids[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", ids[i], ids[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,193 @@
+package tqwp
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrScheduleCancelled is reported for a scheduled task that was cancelled
+// through its ScheduledTask handle before it was due.
+var ErrScheduleCancelled = errors.New("scheduled task cancelled")
+
+// ScheduledTask is a handle to a task waiting to be enqueued at a later time.
+type ScheduledTask struct {
+	task  Task
+	at    time.Time
+	seq   uint64
+	index int
+	sched *scheduler
+}
+
+// Task returns the scheduled task.
+func (st *ScheduledTask) Task() Task {
+	return st.task
+}
+
+// At returns the time the task is due to be enqueued.
+func (st *ScheduledTask) At() time.Time {
+	return st.at
+}
+
+// Cancel removes the task from the schedule. It returns false if the task
+// was already enqueued or cancelled.
+func (st *ScheduledTask) Cancel() bool {
+	s := st.sched
+
+	s.mu.Lock()
+	if st.index < 0 {
+		s.mu.Unlock()
+		return false
+	}
+	heap.Remove(&s.items, st.index)
+	s.mu.Unlock()
+
+	s.pool.cancelTask(st.task, ErrScheduleCancelled)
+	s.pool.taskWg.Done()
+	return true
+}
+
+// scheduleHeap orders scheduled tasks by due time, then by insertion order.
+type scheduleHeap []*ScheduledTask
+
+func (h scheduleHeap) Len() int { return len(h) }
+
+func (h scheduleHeap) Less(i, j int) bool {
+	if !h[i].at.Equal(h[j].at) {
+		return h[i].at.Before(h[j].at)
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h scheduleHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *scheduleHeap) Push(x any) {
+	st := x.(*ScheduledTask)
+	st.index = len(*h)
+	*h = append(*h, st)
+}
+
+func (h *scheduleHeap) Pop() any {
+	old := *h
+	st := old[len(old)-1]
+	old[len(old)-1] = nil
+	st.index = -1
+	*h = old[:len(old)-1]
+	return st
+}
+
+// scheduler holds tasks until they are due and then moves them to the
+// pool's queue. It is used for tasks enqueued with EnqueueAt and EnqueueAfter
+// as well as for retries waiting for their backoff delay.
+type scheduler struct {
+	pool  *WorkerPool
+	mu    sync.Mutex
+	items scheduleHeap
+	seq   uint64
+	wake  chan struct{}
+}
+
+func newScheduler(wp *WorkerPool) *scheduler {
+	return &scheduler{
+		pool: wp,
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// schedule adds task to be enqueued at the given time. The caller must have
+// accounted for the task in the pool's task wait group.
+func (s *scheduler) schedule(task Task, at time.Time) *ScheduledTask {
+	s.mu.Lock()
+	s.seq++
+	st := &ScheduledTask{
+		task:  task,
+		at:    at,
+		seq:   s.seq,
+		sched: s,
+	}
+	heap.Push(&s.items, st)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return st
+}
+
+// run moves due tasks to the queue until ctx is done.
+func (s *scheduler) run(ctx context.Context) {
+	defer s.pool.wg.Done()
+
+	for {
+		s.mu.Lock()
+		var timer *time.Timer
+		var due <-chan time.Time
+		if len(s.items) > 0 {
+			next := s.items[0]
+			if wait := time.Until(next.at); wait > 0 {
+				timer = time.NewTimer(wait)
+				due = timer.C
+			} else {
+				heap.Pop(&s.items)
+				s.mu.Unlock()
+
+				s.pool.requeue(next.task)
+				continue
+			}
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+		case <-s.wake:
+		case <-due:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// cancelAll abandons every task still waiting in the schedule.
+func (s *scheduler) cancelAll(err error) {
+	s.mu.Lock()
+	items := s.items
+	s.items = nil
+	for _, st := range items {
+		st.index = -1
+	}
+	s.mu.Unlock()
+
+	for _, st := range items {
+		s.pool.cancelTask(st.task, err)
+		s.pool.taskWg.Done()
+	}
+}
+
+// EnqueueAt schedules task to be enqueued at the given time. A time in the
+// past enqueues the task as soon as the pool is started. Scheduled tasks are
+// counted in TaskScheduled, and Stop waits for them like for any other task.
+func (wp *WorkerPool) EnqueueAt(task Task, at time.Time) *ScheduledTask {
+	wp.taskWg.Add(1)
+	atomic.AddUint32(&wp.TaskScheduled, 1)
+	wp.metrics.TaskEnqueued()
+	wp.startTaskSpan(context.Background(), task)
+	wp.emitTask(EventEnqueue, task, nil)
+	return wp.scheduler.schedule(task, at)
+}
+
+// EnqueueAfter schedules task to be enqueued once d has elapsed.
+func (wp *WorkerPool) EnqueueAfter(task Task, d time.Duration) *ScheduledTask {
+	return wp.EnqueueAt(task, time.Now().Add(d))
+}
@@ -0,0 +1,92 @@
+package tqwp_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/abdullahnettoor/tqwp"
+)
+
+// flakyTask is a task that always fails, so workers keep retrying it.
+type flakyTask struct {
+	tqwp.TaskModel
+}
+
+func (t *flakyTask) Process() error {
+	return errors.New("service unavailable")
+}
+
+// stopWithin stops wp, failing the test if it does not terminate within d.
+func stopWithin(t *testing.T, wp *tqwp.WorkerPool, d time.Duration) {
+	t.Helper()
+
+	stopped := make(chan struct{})
+	go func() {
+		wp.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(d):
+		t.Fatalf("worker pool did not terminate within %v, tasks are stranded", d)
+	}
+}
+
+// TestRetryStress runs many failing tasks through small queues: retries
+// must never block the workers, nor wait for a pop that never comes.
+func TestRetryStress(t *testing.T) {
+	tests := []struct {
+		name      string
+		workers   uint
+		queueSize uint
+		tasks     int
+	}{
+		{name: "QueueSize1", workers: 16, queueSize: 1, tasks: 5000},
+		{name: "Unbuffered", workers: 1, queueSize: 0, tasks: 100},
+		{name: "UnbufferedManyWorkers", workers: 16, queueSize: 0, tasks: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wp := tqwp.New(&tqwp.WorkerPoolConfig{
+				MaxRetries:   3,
+				NumOfWorkers: tt.workers,
+				QueueSize:    tt.queueSize,
+				Logger:       tqwp.NopLogger{},
+			})
+
+			wp.Start()
+			for i := 0; i < tt.tasks; i++ {
+				wp.EnqueueTask(&flakyTask{})
+			}
+			stopWithin(t, wp, time.Minute)
+
+			if got := int(wp.TaskFailure); got != tt.tasks {
+				t.Fatalf("TaskFailure = %d, want %d", got, tt.tasks)
+			}
+		})
+	}
+}
+
+// TestScheduledTasksWhileBusy schedules tasks coming due while the only
+// worker is busy with a failing task and the queue is unbuffered.
+func TestScheduledTasksWhileBusy(t *testing.T) {
+	const numOfTasks = 50
+	wp := tqwp.New(&tqwp.WorkerPoolConfig{
+		MaxRetries:   2,
+		NumOfWorkers: 1,
+		Logger:       tqwp.NopLogger{},
+	})
+
+	wp.Start()
+	at := time.Now().Add(10 * time.Millisecond)
+	for i := 0; i < numOfTasks; i++ {
+		wp.EnqueueAt(&flakyTask{}, at)
+	}
+	stopWithin(t, wp, time.Minute)
+
+	if wp.TaskFailure != numOfTasks {
+		t.Fatalf("TaskFailure = %d, want %d", wp.TaskFailure, numOfTasks)
+	}
+}
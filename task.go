@@ -1,5 +1,7 @@
 package tqwp
 
+import "context"
+
 // Task represents the interface that defines a unit of work.
 // The Process function must be implemented by users to define custom task behavior.
 type Task interface {
@@ -8,36 +10,56 @@ type Task interface {
 	Process() error
 }
 
-// RetryableTask is an interface that extends Task and manages retries for failed tasks.
-// It allows tasks to be retried up to a specified maxRetries value.
-type retryableTask interface {
-	// Task is embedded to ensure users only need to implement the Process method for custom tasks.
+// ContextTask is an optional interface for tasks that support cancellation.
+// When a task implements ContextTask, workers call ProcessContext with the
+// pool's context instead of Process, so cancelling the context given to
+// StartContext (or calling Stop) is propagated into the running task.
+type ContextTask interface {
 	Task
 
-	// Retry attempts to retry the task, returning true if retries are still allowed.
-	retry(maxRetries uint) bool
+	// ProcessContext is the context-aware variant of Process.
+	// It should return promptly once ctx is done.
+	ProcessContext(ctx context.Context) error
+}
+
+// processTask runs the task, passing ctx to it if it implements ContextTask.
+// A panic raised by the task is recovered and returned as a *PanicError.
+func processTask(ctx context.Context, task Task) (err error) {
+	defer recoverPanic(&err)
 
-	// GetRetry returns the current retry count for the task.
-	getRetry() uint
+	if ct, ok := task.(ContextTask); ok {
+		return ct.ProcessContext(ctx)
+	}
+	return task.Process()
 }
 
 // TaskModel is a base struct that users can embed in their custom tasks
 // to manage retry logic by keeping track of retry attempts.
+// It is the default implementation of RetryableTask and TraceableTask.
+//
+// Its state is unexported, so it does not show up in the encoding of user
+// tasks; Registry.Seal carries it in the Envelope instead.
 type TaskModel struct {
 	retries uint
+	trace   SpanContext
 }
 
-// Retry increments the retry count and returns true if the task
-// can still be retried (i.e., the retry count is below maxRetries).
-func (tm *TaskModel) retry(maxRetries uint) bool {
-	if tm.retries < maxRetries {
-		tm.retries++
-		return true
-	}
-	return false
+// Retries returns the number of retries that have been attempted for the task.
+func (tm *TaskModel) Retries() uint {
+	return tm.retries
 }
 
-// GetRetry returns the number of retries that have been attempted for the task.
-func (tm *TaskModel) getRetry() uint {
-	return tm.retries
+// SetRetries records the number of retries attempted for the task.
+func (tm *TaskModel) SetRetries(n uint) {
+	tm.retries = n
+}
+
+// TraceContext returns the span context of the task.
+func (tm *TaskModel) TraceContext() SpanContext {
+	return tm.trace
+}
+
+// SetTraceContext records the span context of the task.
+func (tm *TaskModel) SetTraceContext(sc SpanContext) {
+	tm.trace = sc
 }
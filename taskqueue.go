@@ -1,22 +1,181 @@
 package tqwp
 
 import (
+	"context"
+	"errors"
 	"sync"
 )
 
+// ErrQueueClosed is returned when pushing to a closed queue, or popping from
+// a closed queue that has no tasks left.
+var ErrQueueClosed = errors.New("queue closed")
+
+// QueueKind selects the built-in queue implementation used by a WorkerPool.
+type QueueKind uint8
+
+const (
+	// FIFOQueue processes tasks in the order they were enqueued.
+	// It is backed by a TaskQueue and is the default.
+	FIFOQueue QueueKind = iota
+
+	// PriorityQueue processes tasks with a higher priority first.
+	// It is backed by a PriorityTaskQueue.
+	PriorityQueue
+
+	// UnboundedQueue processes tasks in FIFO order without a fixed
+	// capacity, ignoring QueueSize. It is backed by an UnboundedTaskQueue.
+	UnboundedQueue
+)
+
+// QueueBackend is the queue a WorkerPool pulls its tasks from.
+// Implementations must be safe for concurrent use.
+//
+// Every task handed out by Pop is eventually reported back with either Ack
+// or Nack, which lets persistent backends know when a task can be forgotten.
+type QueueBackend interface {
+	// Push adds a task, blocking while the queue is full until ctx is done.
+	Push(ctx context.Context, task Task) error
+
+	// Pop removes and returns the next task, blocking until a task is
+	// available or ctx is done. Once the queue is closed, Pop keeps
+	// returning the remaining tasks and then ErrQueueClosed.
+	Pop(ctx context.Context) (Task, error)
+
+	// Ack reports that a popped task reached its final outcome, success or
+	// failure, and will not be pushed again.
+	Ack(task Task) error
+
+	// Nack reports that processing of a popped task ended without a final
+	// outcome: the task failed with err and will be pushed again for a
+	// retry, or it was abandoned because the pool stopped.
+	Nack(task Task, err error) error
+
+	// Len returns the number of tasks waiting in the queue.
+	Len() int
+
+	// Close closes the queue. Pushes blocked on a full queue return
+	// ErrQueueClosed, and tasks already queued can still be popped.
+	Close() error
+}
+
+// newQueueBackend returns the queue configured by cfg.
+func newQueueBackend(cfg *WorkerPoolConfig) QueueBackend {
+	if cfg.Queue != nil {
+		return cfg.Queue
+	}
+	switch cfg.QueueKind {
+	case PriorityQueue:
+		return NewPriorityTaskQueue(cfg.QueueSize, cfg.PriorityAging)
+	case UnboundedQueue:
+		return NewUnboundedTaskQueue(cfg.QueueMemoryLimit)
+	}
+	return NewTaskQueue(cfg.QueueSize)
+}
+
+// TaskQueue is a bounded FIFO QueueBackend backed by a buffered channel.
+// It is the default queue of a WorkerPool.
 type TaskQueue struct {
-	Tasks chan Task
-	mu    sync.Mutex
+	Tasks     chan Task
+	mu        sync.RWMutex
+	closed    bool
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 func NewTaskQueue(size uint) *TaskQueue {
 	return &TaskQueue{
 		Tasks: make(chan Task, size),
+		done:  make(chan struct{}),
 	}
 }
 
 func (tq *TaskQueue) Enqueue(task Task) {
-	tq.mu.Lock()
-	defer tq.mu.Unlock()
-	tq.Tasks <- task
+	tq.Push(context.Background(), task)
+}
+
+// Push implements QueueBackend.
+func (tq *TaskQueue) Push(ctx context.Context, task Task) error {
+	tq.mu.RLock()
+	defer tq.mu.RUnlock()
+
+	if tq.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case tq.Tasks <- task:
+		return nil
+	case <-tq.done:
+		return ErrQueueClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryPush implements TryPusher.
+func (tq *TaskQueue) TryPush(task Task) error {
+	tq.mu.RLock()
+	defer tq.mu.RUnlock()
+
+	if tq.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case tq.Tasks <- task:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// EvictOldest implements Evicter.
+func (tq *TaskQueue) EvictOldest() (Task, bool) {
+	select {
+	case task, ok := <-tq.Tasks:
+		return task, ok
+	default:
+		return nil, false
+	}
+}
+
+// Pop implements QueueBackend.
+func (tq *TaskQueue) Pop(ctx context.Context) (Task, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case task, ok := <-tq.Tasks:
+		if !ok {
+			return nil, ErrQueueClosed
+		}
+		return task, nil
+	}
+}
+
+// Ack implements QueueBackend. It is a no-op.
+func (tq *TaskQueue) Ack(task Task) error {
+	return nil
+}
+
+// Nack implements QueueBackend. It is a no-op.
+func (tq *TaskQueue) Nack(task Task, err error) error {
+	return nil
+}
+
+// Len implements QueueBackend.
+func (tq *TaskQueue) Len() int {
+	return len(tq.Tasks)
+}
+
+// Close implements QueueBackend.
+func (tq *TaskQueue) Close() error {
+	tq.closeOnce.Do(func() {
+		close(tq.done)
+
+		// Wait for blocked pushes to observe done before closing the channel.
+		tq.mu.Lock()
+		defer tq.mu.Unlock()
+
+		tq.closed = true
+		close(tq.Tasks)
+	})
+	return nil
 }
@@ -0,0 +1,105 @@
+package tqwp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTaskTimeout is returned for a task attempt that did not finish within
+// its timeout, even if it eventually succeeded. Timed-out attempts are
+// treated as failures and retried like any other error.
+var ErrTaskTimeout = errors.New("task timed out")
+
+// TimeoutTask is an optional interface for tasks that need a deadline other
+// than the pool-wide WorkerPoolConfig.TaskTimeout.
+type TimeoutTask interface {
+	Task
+
+	// Timeout returns the maximum duration of a single attempt of the task.
+	// A value of zero or less disables the timeout for the task.
+	Timeout() time.Duration
+}
+
+// DeadlinePolicy controls how a worker reacts when a task exceeds its timeout.
+type DeadlinePolicy uint8
+
+const (
+	// DeadlineAbandon cancels the task's context and stops waiting for it
+	// right away, so a task that ignores its context cannot pin the worker.
+	// The abandoned attempt keeps running in the background until it
+	// returns, and the task is only retried once it has returned, so it
+	// never runs concurrently with its own retry. Stop waits for abandoned
+	// attempts to return. It is the default.
+	DeadlineAbandon DeadlinePolicy = iota
+
+	// DeadlineCooperative only cancels the task's context and waits for the
+	// task to return. Only tasks implementing ContextTask see the cancelled
+	// context, so a task that ignores it pins its worker until it returns.
+	DeadlineCooperative
+)
+
+// taskTimeout returns the timeout that applies to a single attempt of task.
+func (wp *WorkerPool) taskTimeout(task Task) time.Duration {
+	if tt, ok := task.(TimeoutTask); ok {
+		return tt.Timeout()
+	}
+	return wp.timeout
+}
+
+// runTask runs a single attempt of task through the middleware chain with
+// ctx, a context derived from the pool's context, bounded by its timeout if
+// any. An attempt that exceeds the timeout returns an error wrapping
+// ErrTaskTimeout, even if it succeeded once its deadline had passed. If the attempt was abandoned under DeadlineAbandon,
+// abandoned is closed once it eventually returns; it is nil otherwise.
+func (wp *WorkerPool) runTask(ctx context.Context, task Task) (abandoned <-chan struct{}, err error) {
+	timeout := wp.taskTimeout(task)
+	if timeout <= 0 {
+		return nil, wp.processAttempt(ctx, task)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	timedOut := func() bool {
+		return errors.Is(ctx.Err(), context.DeadlineExceeded) && wp.ctx.Err() == nil
+	}
+	// returnedErr is the outcome of an attempt that returned with err.
+	returnedErr := func(err error) error {
+		switch {
+		case !timedOut():
+			return err
+		case err == nil:
+			return fmt.Errorf("%w after %v", ErrTaskTimeout, timeout)
+		default:
+			return fmt.Errorf("%w after %v: %v", ErrTaskTimeout, timeout, err)
+		}
+	}
+
+	if wp.deadlinePolicy == DeadlineCooperative {
+		return nil, returnedErr(wp.processAttempt(ctx, task))
+	}
+
+	done := make(chan error, 1)
+	returned := make(chan struct{})
+	// The attempt is counted like a worker, so Stop waits for it even if
+	// it is abandoned.
+	wp.wg.Add(1)
+	go func() {
+		defer wp.wg.Done()
+		done <- wp.processAttempt(ctx, task)
+		close(returned)
+	}()
+
+	select {
+	case err := <-done:
+		return nil, returnedErr(err)
+	case <-ctx.Done():
+		if timedOut() {
+			return returned, fmt.Errorf("%w after %v", ErrTaskTimeout, timeout)
+		}
+		// The pool was cancelled, wait for the task to observe it.
+		return nil, <-done
+	}
+}
@@ -0,0 +1,79 @@
+package tqwp_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/abdullahnettoor/tqwp"
+)
+
+// sleepTask is a task ignoring its context, like a stalled http.Get.
+type sleepTask struct {
+	tqwp.TaskModel
+	d       time.Duration
+	running *int32
+}
+
+func (t *sleepTask) Process() error {
+	if t.running != nil && atomic.AddInt32(t.running, 1) > 1 {
+		panic("attempts of a task overlap")
+	}
+	time.Sleep(t.d)
+	if t.running != nil {
+		atomic.AddInt32(t.running, -1)
+	}
+	return nil
+}
+
+// TestTimeoutFreesWorker checks that, by default, a task ignoring its
+// context fails with a timeout and does not pin its worker.
+func TestTimeoutFreesWorker(t *testing.T) {
+	wp := tqwp.New(&tqwp.WorkerPoolConfig{
+		NumOfWorkers: 1,
+		QueueSize:    10,
+		TaskTimeout:  50 * time.Millisecond,
+		Logger:       tqwp.NopLogger{},
+	})
+
+	done := make(chan struct{})
+	wp.Start()
+	wp.EnqueueTask(&sleepTask{d: 300 * time.Millisecond})
+	wp.EnqueueTask(&funcTask{fn: func() { close(done) }})
+
+	select {
+	case <-done:
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("the worker is pinned by the timed-out task")
+	}
+	stopWithin(t, wp, time.Minute)
+
+	if wp.TaskFailure != 1 || wp.TaskTimeouts != 1 || wp.TaskSuccess != 1 {
+		t.Fatalf("TaskFailure = %d, TaskTimeouts = %d, TaskSuccess = %d, want 1, 1, 1",
+			wp.TaskFailure, wp.TaskTimeouts, wp.TaskSuccess)
+	}
+}
+
+// TestTimeoutPolicies checks that a late success counts as a timeout under
+// both policies, and that retries never overlap the timed-out attempt.
+func TestTimeoutPolicies(t *testing.T) {
+	for _, policy := range []tqwp.DeadlinePolicy{tqwp.DeadlineAbandon, tqwp.DeadlineCooperative} {
+		wp := tqwp.New(&tqwp.WorkerPoolConfig{
+			NumOfWorkers:   4,
+			MaxRetries:     2,
+			QueueSize:      10,
+			TaskTimeout:    10 * time.Millisecond,
+			DeadlinePolicy: policy,
+			Logger:         tqwp.NopLogger{},
+		})
+
+		wp.Start()
+		wp.EnqueueTask(&sleepTask{d: 30 * time.Millisecond, running: new(int32)})
+		stopWithin(t, wp, time.Minute)
+
+		if wp.TaskFailure != 1 || wp.TaskTimeouts != 3 {
+			t.Fatalf("policy %d: TaskFailure = %d, TaskTimeouts = %d, want 1, 3",
+				policy, wp.TaskFailure, wp.TaskTimeouts)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package tqwp
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+// SpanData is the record of an ended span, as handed to a SpanExporter.
+type SpanData struct {
+	Name        string
+	SpanContext SpanContext
+
+	// Parent is the span context of the parent span. It is invalid for
+	// the root span of a trace.
+	Parent SpanContext
+
+	StartTime  time.Time
+	EndTime    time.Time
+	Attributes []Field
+
+	// Err is the last error recorded on the span, if any.
+	Err error
+}
+
+// Attribute returns the value of the last attribute named key.
+func (s SpanData) Attribute(key string) (any, bool) {
+	for i := len(s.Attributes) - 1; i >= 0; i-- {
+		if s.Attributes[i].Key == key {
+			return s.Attributes[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+// SpanExporter receives the spans of a tracer returned by NewTracer as they
+// end. Implementations must be safe for concurrent use.
+type SpanExporter interface {
+	ExportSpan(span SpanData)
+}
+
+// NewTracer returns a Tracer generating random trace and span IDs and
+// handing every ended span to exporter.
+func NewTracer(exporter SpanExporter) Tracer {
+	return &tracer{exporter: exporter}
+}
+
+// tracer is the Tracer returned by NewTracer.
+type tracer struct {
+	exporter SpanExporter
+}
+
+// Start implements Tracer.
+func (t *tracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent := SpanContextFromContext(ctx)
+	sc := SpanContext{TraceID: parent.TraceID}
+	if !parent.IsValid() {
+		rand.Read(sc.TraceID[:])
+	}
+	rand.Read(sc.SpanID[:])
+
+	s := &span{
+		exporter: t.exporter,
+		data: SpanData{
+			Name:        name,
+			SpanContext: sc,
+			Parent:      parent,
+			StartTime:   time.Now(),
+		},
+	}
+	return ContextWithSpanContext(ctx, sc), s
+}
+
+// span is the Span started by tracer.
+type span struct {
+	exporter SpanExporter
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SpanContext implements Span.
+func (s *span) SpanContext() SpanContext {
+	return s.data.SpanContext
+}
+
+// SetAttributes implements Span.
+func (s *span) SetAttributes(attrs ...Field) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Attributes = append(s.data.Attributes, attrs...)
+	}
+}
+
+// RecordError implements Span.
+func (s *span) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Err = err
+	}
+}
+
+// End implements Span.
+func (s *span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	s.exporter.ExportSpan(data)
+}
+
+// InMemoryExporter is a SpanExporter keeping the spans in memory, so tests
+// can inspect the traces of a pool:
+//
+//	spans := tqwp.NewInMemoryExporter()
+//	wp := tqwp.New(&tqwp.WorkerPoolConfig{
+//		NumOfWorkers: 1,
+//		Tracer:       tqwp.NewTracer(spans),
+//	})
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// NewInMemoryExporter returns an empty InMemoryExporter.
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpan implements SpanExporter.
+func (e *InMemoryExporter) ExportSpan(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans returns the exported spans in the order they ended.
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// Reset discards the exported spans.
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
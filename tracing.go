@@ -0,0 +1,220 @@
+package tqwp
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+)
+
+// TraceID identifies a trace, the tree of spans describing an operation.
+type TraceID [16]byte
+
+// IsValid reports whether id is not all zeros.
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// String returns the hex encoding of id.
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// MarshalText encodes id in hex.
+func (id TraceID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText decodes id from hex.
+func (id *TraceID) UnmarshalText(text []byte) error {
+	return decodeID(id[:], text)
+}
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// IsValid reports whether id is not all zeros.
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// String returns the hex encoding of id.
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// MarshalText encodes id in hex.
+func (id SpanID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText decodes id from hex.
+func (id *SpanID) UnmarshalText(text []byte) error {
+	return decodeID(id[:], text)
+}
+
+// decodeID decodes the hex text of a trace or span ID into dst.
+func decodeID(dst, text []byte) error {
+	if hex.DecodedLen(len(text)) != len(dst) {
+		return fmt.Errorf("invalid ID %q: want %d hex digits", text, hex.EncodedLen(len(dst)))
+	}
+	_, err := hex.Decode(dst, text)
+	return err
+}
+
+// SpanContext identifies a span, so spans started elsewhere, possibly in
+// another process, can be attached to it.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+}
+
+// IsValid reports whether sc identifies a span.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx carrying sc, so spans
+// started with the returned context become children of sc.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context carried by ctx, or an
+// invalid SpanContext if there is none.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc
+}
+
+// Tracer starts the spans a WorkerPool uses to trace tasks. Its methods
+// follow the OpenTelemetry tracing API, so a Tracer is easily adapted to an
+// OpenTelemetry tracer. Implementations must be safe for concurrent use.
+type Tracer interface {
+	// Start starts a span named name. The span is a child of the span
+	// carried by ctx, if any, and the returned context carries the new
+	// span. Tracers must honour span contexts added with
+	// ContextWithSpanContext, which the pool uses to attach the spans of
+	// task attempts to the span of their task.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a timed operation within a trace.
+type Span interface {
+	// SpanContext returns the identity of the span.
+	SpanContext() SpanContext
+
+	// SetAttributes sets attributes describing the span.
+	SetAttributes(attrs ...Field)
+
+	// RecordError records err as the cause of the span's failure.
+	RecordError(err error)
+
+	// End completes the span. Calls after the first one are ignored.
+	End()
+}
+
+// TraceableTask is an optional interface for tasks that carry the span
+// context of their task span through the queue. TaskModel implements it,
+// and Registry.Seal carries the span context in the Envelope, so it
+// survives serialization by a DurableQueue. Attempts
+// of tasks that do not implement it are traced without a task span.
+type TraceableTask interface {
+	Task
+
+	// TraceContext returns the span context of the task.
+	TraceContext() SpanContext
+
+	// SetTraceContext records the span context of the task.
+	SetTraceContext(sc SpanContext)
+}
+
+// Names of the spans started by a WorkerPool.
+const (
+	// TaskSpanName is the name of the span covering a task from the time
+	// it is enqueued until its final outcome, across all its attempts.
+	TaskSpanName = "tqwp.task"
+
+	// AttemptSpanName is the name of the span covering a single attempt
+	// of a task, a child of the task span.
+	AttemptSpanName = "tqwp.attempt"
+)
+
+// startTaskSpan starts the span of task as a child of the span carried by
+// ctx, and stores its span context in the task. It is a no-op without a
+// tracer or for tasks that do not implement TraceableTask.
+func (wp *WorkerPool) startTaskSpan(ctx context.Context, task Task) {
+	if wp.tracer == nil {
+		return
+	}
+	tt, ok := task.(TraceableTask)
+	if !ok {
+		return
+	}
+
+	_, span := wp.tracer.Start(ctx, TaskSpanName)
+	span.SetAttributes(Field{Key: "task", Value: taskID(task)})
+	sc := span.SpanContext()
+	tt.SetTraceContext(sc)
+
+	wp.traceMu.Lock()
+	wp.taskSpans[sc.SpanID] = span
+	wp.traceMu.Unlock()
+}
+
+// endTaskSpan ends the span of task with its final outcome err. Tasks
+// replayed by a persistent queue after a restart have no span to end.
+func (wp *WorkerPool) endTaskSpan(task Task, err error) {
+	if wp.tracer == nil {
+		return
+	}
+	tt, ok := task.(TraceableTask)
+	if !ok {
+		return
+	}
+
+	wp.traceMu.Lock()
+	span, ok := wp.taskSpans[tt.TraceContext().SpanID]
+	delete(wp.taskSpans, tt.TraceContext().SpanID)
+	wp.traceMu.Unlock()
+	if !ok {
+		return
+	}
+
+	if rt, ok := task.(RetryableTask); ok {
+		span.SetAttributes(Field{Key: "retries", Value: rt.Retries()})
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// startAttemptSpan starts the span of an attempt of task by worker, as a
+// child of the task span. It returns the context to run the attempt with,
+// and a function ending the span. Without a tracer, it returns ctx and a
+// no-op.
+func (wp *WorkerPool) startAttemptSpan(ctx context.Context, worker int, task Task, attempt uint) (context.Context, func(err error, fields ...Field)) {
+	if wp.tracer == nil {
+		return ctx, func(error, ...Field) {}
+	}
+	if tt, ok := task.(TraceableTask); ok && tt.TraceContext().IsValid() {
+		ctx = ContextWithSpanContext(ctx, tt.TraceContext())
+	}
+
+	ctx, span := wp.tracer.Start(ctx, AttemptSpanName)
+	span.SetAttributes(
+		Field{Key: "worker", Value: worker},
+		Field{Key: "task", Value: taskID(task)},
+		Field{Key: "attempt", Value: attempt},
+		Field{Key: "retries", Value: attempt - 1},
+	)
+	return ctx, func(err error, fields ...Field) {
+		span.SetAttributes(fields...)
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}
+}
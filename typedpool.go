@@ -0,0 +1,207 @@
+package tqwp
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Result is the outcome of processing a single input of a TypedPool.
+type Result[In, Out any] struct {
+	// Index is the position of the input in the input stream, starting at 0.
+	Index int
+
+	// Input is the value the handler was called with.
+	Input In
+
+	// Output is the value returned by the successful attempt.
+	Output Out
+
+	// Err is the error of the final attempt, after all retries.
+	Err error
+
+	// Attempts is the number of times the handler was called for Input.
+	Attempts uint
+}
+
+// TypedPoolConfig holds configuration parameters for TypedPool.
+type TypedPoolConfig struct {
+	WorkerPoolConfig
+
+	// Ordered makes the pool emit results in the order of their inputs
+	// instead of the order in which they complete.
+	Ordered bool
+}
+
+// TypedPool processes a homogeneous stream of inputs with a single handler,
+// without having to wrap every input in a custom task. Inputs are processed
+// by the embedded WorkerPool, so retries, timeouts, dead letters and the
+// processing statistics work just like they do for regular tasks.
+//
+// A TypedPool runs a single stream: Run or RunSlice must be called only once.
+type TypedPool[In, Out any] struct {
+	*WorkerPool
+	handler func(ctx context.Context, in In) (Out, error)
+	ordered bool
+}
+
+// NewTypedPool initializes and returns a new TypedPool that calls handler
+// for every input.
+func NewTypedPool[In, Out any](cfg *TypedPoolConfig, handler func(ctx context.Context, in In) (Out, error)) *TypedPool[In, Out] {
+	return &TypedPool[In, Out]{
+		WorkerPool: New(&cfg.WorkerPoolConfig),
+		handler:    handler,
+		ordered:    cfg.Ordered,
+	}
+}
+
+// Run starts the pool and processes every value received from inputs. The
+// returned channel emits one result per input and is closed once inputs is
+// closed and all inputs are processed, at which point the pool is stopped.
+// Cancelling ctx cancels the pool; results still pending then may be dropped.
+func (p *TypedPool[In, Out]) Run(ctx context.Context, inputs <-chan In) <-chan Result[In, Out] {
+	results := make(chan Result[In, Out])
+	out := make(chan Result[In, Out])
+
+	p.StartContext(ctx)
+
+	go func() {
+		defer close(results)
+		defer p.Stop()
+
+		for index := 0; ; index++ {
+			select {
+			case <-ctx.Done():
+				return
+			case in, ok := <-inputs:
+				if !ok {
+					return
+				}
+				task := &typedTask[In, Out]{
+					pool:    p,
+					ctx:     ctx,
+					results: results,
+					result:  Result[In, Out]{Index: index, Input: in},
+				}
+				// A task rejected by the overflow policy still emits its result.
+				if err := p.enqueueTaskContext(ctx, task); err != nil && !errors.Is(err, ErrQueueFull) {
+					return
+				}
+			}
+		}
+	}()
+
+	go p.collect(ctx, results, out)
+	return out
+}
+
+// RunSlice is like Run, but takes its inputs from a slice.
+func (p *TypedPool[In, Out]) RunSlice(ctx context.Context, inputs []In) <-chan Result[In, Out] {
+	ch := make(chan In)
+	go func() {
+		defer close(ch)
+		for _, in := range inputs {
+			select {
+			case ch <- in:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return p.Run(ctx, ch)
+}
+
+// collect forwards results to out until results is closed, restoring the
+// order of the inputs first if the pool is ordered.
+func (p *TypedPool[In, Out]) collect(ctx context.Context, results <-chan Result[In, Out], out chan<- Result[In, Out]) {
+	defer close(out)
+
+	emit := func(r Result[In, Out]) {
+		select {
+		case out <- r:
+		case <-ctx.Done():
+		}
+	}
+
+	if !p.ordered {
+		for r := range results {
+			emit(r)
+		}
+		return
+	}
+
+	next := 0
+	pending := make(map[int]Result[In, Out])
+	for r := range results {
+		pending[r.Index] = r
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			emit(r)
+			next++
+		}
+	}
+
+	// Inputs dropped on cancellation leave gaps, flush what is left in order.
+	for len(pending) > 0 {
+		if r, ok := pending[next]; ok {
+			delete(pending, next)
+			emit(r)
+		}
+		next++
+	}
+}
+
+// typedTask adapts a single input of a TypedPool to the Task interface.
+type typedTask[In, Out any] struct {
+	TaskModel
+	pool    *TypedPool[In, Out]
+	ctx     context.Context
+	results chan<- Result[In, Out]
+	mu      sync.Mutex
+	result  Result[In, Out]
+}
+
+// Process implements Task.
+func (t *typedTask[In, Out]) Process() error {
+	return t.ProcessContext(context.Background())
+}
+
+// ProcessContext implements ContextTask.
+func (t *typedTask[In, Out]) ProcessContext(ctx context.Context) error {
+	t.mu.Lock()
+	t.result.Attempts++
+	in := t.result.Input
+	t.mu.Unlock()
+
+	value, err := t.pool.handler(ctx, in)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.result.Output = value
+	t.mu.Unlock()
+	return nil
+}
+
+// complete implements completer.
+func (t *typedTask[In, Out]) complete(err error) {
+	t.mu.Lock()
+	r := t.result
+	t.mu.Unlock()
+
+	if err != nil {
+		var zero Out
+		r.Output = zero
+		r.Err = err
+	}
+
+	select {
+	case t.results <- r:
+	case <-t.ctx.Done():
+	}
+}
@@ -0,0 +1,259 @@
+package tqwp
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// segmentCapacity is the number of tasks held by a single segment of an
+// UnboundedTaskQueue.
+const segmentCapacity = 256
+
+// interfaceSize is the memory taken by a Task interface value.
+const interfaceSize = 16
+
+// SizedTask is an optional interface for tasks that know how much memory
+// they hold. The memory limit of an UnboundedTaskQueue uses it instead of
+// its default estimate, which only accounts for the task struct itself and
+// not for the strings, slices or maps it references.
+type SizedTask interface {
+	Task
+
+	// MemorySize returns the approximate memory held by the task, in bytes.
+	MemorySize() int
+}
+
+// taskMemorySize estimates the memory held by task.
+func taskMemorySize(task Task) uint64 {
+	if st, ok := task.(SizedTask); ok {
+		return uint64(st.MemorySize())
+	}
+	t := reflect.TypeOf(task)
+	if t.Kind() == reflect.Pointer {
+		return uint64(t.Elem().Size()) + interfaceSize
+	}
+	return uint64(t.Size()) + interfaceSize
+}
+
+// taskSegment is a fixed-size block of an UnboundedTaskQueue. Tasks are
+// appended at tail and removed at head; a segment is recycled once every
+// task in it was removed.
+type taskSegment struct {
+	tasks [segmentCapacity]Task
+	sizes [segmentCapacity]uint64
+	head  int
+	tail  int
+	next  *taskSegment
+}
+
+// UnboundedTaskQueue is a FIFO QueueBackend without a fixed capacity.
+// Tasks are stored in a list of fixed-size segments that is extended as the
+// queue grows and released as it drains, so memory follows the number of
+// waiting tasks.
+//
+// An optional soft memory limit bounds the queue: once the estimated memory
+// of the waiting tasks reaches the limit, the queue switches to backpressure
+// and Push blocks, like on a full bounded queue, until tasks are popped.
+type UnboundedTaskQueue struct {
+	mu     sync.Mutex
+	head   *taskSegment
+	tail   *taskSegment
+	spare  *taskSegment
+	len    int
+	memory uint64
+	limit  uint64
+	closed bool
+	wake   chan struct{}
+}
+
+// NewUnboundedTaskQueue returns an UnboundedTaskQueue applying backpressure
+// once its tasks hold memoryLimit bytes. A memoryLimit of zero means no limit.
+func NewUnboundedTaskQueue(memoryLimit uint64) *UnboundedTaskQueue {
+	seg := &taskSegment{}
+	return &UnboundedTaskQueue{
+		head:  seg,
+		tail:  seg,
+		limit: memoryLimit,
+		wake:  make(chan struct{}),
+	}
+}
+
+// full reports whether the queue applies backpressure.
+// It must be called with uq.mu held.
+func (uq *UnboundedTaskQueue) full() bool {
+	return uq.limit > 0 && uq.memory >= uq.limit
+}
+
+// broadcast wakes up every goroutine waiting for the queue to change.
+// It must be called with uq.mu held.
+func (uq *UnboundedTaskQueue) broadcast() {
+	close(uq.wake)
+	uq.wake = make(chan struct{})
+}
+
+// Enqueue adds a task to the queue, blocking while the memory limit is exceeded.
+func (uq *UnboundedTaskQueue) Enqueue(task Task) {
+	uq.Push(context.Background(), task)
+}
+
+// Push implements QueueBackend.
+func (uq *UnboundedTaskQueue) Push(ctx context.Context, task Task) error {
+	uq.mu.Lock()
+	for !uq.closed && uq.full() {
+		wake := uq.wake
+		uq.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		uq.mu.Lock()
+	}
+	defer uq.mu.Unlock()
+
+	if uq.closed {
+		return ErrQueueClosed
+	}
+	uq.push(task)
+	return nil
+}
+
+// TryPush implements TryPusher.
+func (uq *UnboundedTaskQueue) TryPush(task Task) error {
+	uq.mu.Lock()
+	defer uq.mu.Unlock()
+
+	if uq.closed {
+		return ErrQueueClosed
+	}
+	if uq.full() {
+		return ErrQueueFull
+	}
+	uq.push(task)
+	return nil
+}
+
+// push appends task, extending the queue with a new segment if the last one
+// is full. It must be called with uq.mu held.
+func (uq *UnboundedTaskQueue) push(task Task) {
+	if uq.tail.tail == segmentCapacity {
+		seg := uq.spare
+		if seg == nil {
+			seg = &taskSegment{}
+		}
+		uq.spare = nil
+		uq.tail.next = seg
+		uq.tail = seg
+	}
+
+	size := taskMemorySize(task)
+	seg := uq.tail
+	seg.tasks[seg.tail] = task
+	seg.sizes[seg.tail] = size
+	seg.tail++
+
+	uq.len++
+	uq.memory += size
+	uq.broadcast()
+}
+
+// pop removes the first task, releasing its segment once it is drained.
+// The queue must not be empty and uq.mu must be held.
+func (uq *UnboundedTaskQueue) pop() Task {
+	seg := uq.head
+	task := seg.tasks[seg.head]
+	uq.memory -= seg.sizes[seg.head]
+	seg.tasks[seg.head] = nil
+	seg.head++
+	uq.len--
+
+	if seg.head == seg.tail {
+		if seg == uq.tail {
+			// The queue is empty, reuse the segment from the start.
+			seg.head, seg.tail = 0, 0
+		} else if seg.head == segmentCapacity {
+			// Keep a single spare segment, so a queue hovering around a
+			// segment boundary does not allocate on every push.
+			uq.head = seg.next
+			*seg = taskSegment{}
+			if uq.spare == nil {
+				uq.spare = seg
+			}
+		}
+	}
+	uq.broadcast()
+	return task
+}
+
+// Pop implements QueueBackend.
+func (uq *UnboundedTaskQueue) Pop(ctx context.Context) (Task, error) {
+	uq.mu.Lock()
+	for uq.len == 0 {
+		if uq.closed {
+			uq.mu.Unlock()
+			return nil, ErrQueueClosed
+		}
+		wake := uq.wake
+		uq.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		uq.mu.Lock()
+	}
+	defer uq.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return uq.pop(), nil
+}
+
+// EvictOldest implements Evicter.
+func (uq *UnboundedTaskQueue) EvictOldest() (Task, bool) {
+	uq.mu.Lock()
+	defer uq.mu.Unlock()
+
+	if uq.len == 0 {
+		return nil, false
+	}
+	return uq.pop(), true
+}
+
+// Ack implements QueueBackend. It is a no-op.
+func (uq *UnboundedTaskQueue) Ack(task Task) error {
+	return nil
+}
+
+// Nack implements QueueBackend. It is a no-op.
+func (uq *UnboundedTaskQueue) Nack(task Task, err error) error {
+	return nil
+}
+
+// Len implements QueueBackend.
+func (uq *UnboundedTaskQueue) Len() int {
+	uq.mu.Lock()
+	defer uq.mu.Unlock()
+	return uq.len
+}
+
+// MemoryUsage returns the estimated memory held by the waiting tasks, in bytes.
+func (uq *UnboundedTaskQueue) MemoryUsage() uint64 {
+	uq.mu.Lock()
+	defer uq.mu.Unlock()
+	return uq.memory
+}
+
+// Close implements QueueBackend.
+func (uq *UnboundedTaskQueue) Close() error {
+	uq.mu.Lock()
+	defer uq.mu.Unlock()
+
+	uq.closed = true
+	uq.broadcast()
+	return nil
+}
@@ -1,7 +1,8 @@
 package tqwp
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -20,28 +21,183 @@ type WorkerPool struct {
 	// TaskFailure holds the count of tasks that failed even after retries.
 	TaskFailure uint32
 
+	// TaskScheduled holds the count of tasks enqueued with EnqueueAt or EnqueueAfter.
+	TaskScheduled uint32
+
+	// TaskTimeouts holds the count of task attempts that exceeded their timeout.
+	// Each timed-out attempt is also handled as a regular failure.
+	TaskTimeouts uint32
+
+	// TaskPanics holds the count of task attempts that panicked.
+	// Each panic is also handled as a regular failure.
+	TaskPanics uint32
+
+	// TaskCancelled holds the count of tasks that were abandoned because
+	// the pool's context was cancelled before they could complete.
+	TaskCancelled uint32
+
+	// TaskRejected holds the count of tasks refused with ErrQueueFull
+	// because the queue was full.
+	TaskRejected uint32
+
+	// TaskDropped holds the count of tasks discarded by the overflow policy.
+	TaskDropped uint32
+
+	// TaskSpilled holds the count of tasks that waited in the overflow
+	// buffer because the queue was full.
+	TaskSpilled uint32
+
 	// CompletedIn tracks the time taken for processing tasks.
 	// It is available only after the Stop function is called.
 	CompletedIn time.Duration
 
-	numOfWorkers uint
-	queue        *TaskQueue
-	wg           *sync.WaitGroup
-	taskWg       *sync.WaitGroup
-	maxRetries   uint
-	startTime    time.Time
+	numOfWorkers    uint
+	workersMu       sync.Mutex
+	workers         []*workerHandle
+	lastWorkerID    int
+	started         bool
+	stopped         bool
+	autoscaler      *autoscaler
+	busyWorkers     int32
+	popped          atomic.Uint64
+	queue           QueueBackend
+	wg              *sync.WaitGroup
+	taskWg          *sync.WaitGroup
+	startTime       time.Time
+	timeout         time.Duration
+	ctx             context.Context
+	cancel          context.CancelFunc
+	deadlinePolicy  DeadlinePolicy
+	panicHandler    PanicHandler
+	retryPolicy     RetryPolicy
+	deadLetters     DeadLetterSink
+	scheduler       *scheduler
+	retries         retryLane
+	feederWake      chan struct{}
+	cronMu          sync.Mutex
+	cronJobs        map[*CronJob]struct{}
+	cronCtx         context.Context
+	cronCancel      context.CancelFunc
+	overflow        OverflowPolicy
+	spillMu         sync.Mutex
+	spill           []Task
+	spillSize       int
+	logger          Logger
+	metrics         MetricsSink
+	tracer          Tracer
+	traceMu         sync.Mutex
+	taskSpans       map[SpanID]Span
+	listenersMu     sync.Mutex
+	listeners       []*Subscription
+	listenersClosed bool
+	listenerWg      sync.WaitGroup
+	listenerTimeout time.Duration
+	eventBufferSize int
+	handler         Handler
 }
 
 // WorkerPoolConfig holds configuration parameters for WorkerPool.
 type WorkerPoolConfig struct {
 	// NumOfWorkers specifies the number of workers in the pool.
+	// With Autoscale, it is the initial number of workers.
 	NumOfWorkers uint
 
+	// Autoscale enables the autoscaler, which adjusts the number of
+	// workers to the load while the pool is running.
+	Autoscale *AutoscaleConfig
+
 	// MaxRetries specifies the maximum retry attempts for failed tasks.
+	// It is ignored when RetryPolicy is set.
 	MaxRetries uint
 
 	// QueueSize specifies the size of task can be hold by TaskQueue
 	QueueSize uint
+
+	// Queue specifies a custom queue backend, such as a persistent or
+	// remote queue. When set, QueueSize, QueueKind, PriorityAging and
+	// QueueMemoryLimit are ignored.
+	Queue QueueBackend
+
+	// QueueKind selects the built-in queue implementation.
+	// It defaults to FIFOQueue.
+	QueueKind QueueKind
+
+	// PriorityAging specifies how long a task waits in a PriorityQueue
+	// before its priority is raised by one, so low-priority tasks are not
+	// starved by a steady stream of urgent ones. Zero disables aging.
+	PriorityAging time.Duration
+
+	// QueueMemoryLimit specifies the soft memory limit, in bytes, of an
+	// UnboundedQueue. Once the waiting tasks are estimated to hold that
+	// much memory, enqueueing is subject to the Overflow policy as if the
+	// queue were full. Zero means no limit.
+	QueueMemoryLimit uint64
+
+	// Overflow specifies what happens to tasks enqueued while the queue is
+	// full. It defaults to OverflowBlock.
+	Overflow OverflowPolicy
+
+	// SpillSize specifies the maximum number of tasks held in the overflow
+	// buffer of the OverflowSpill policy. Zero means no limit.
+	SpillSize uint
+
+	// TaskTimeout specifies the maximum duration of a single task attempt.
+	// Tasks can override it by implementing TimeoutTask. Zero means no timeout.
+	TaskTimeout time.Duration
+
+	// DeadlinePolicy specifies how workers treat a task that exceeds its
+	// timeout. It defaults to DeadlineAbandon.
+	DeadlinePolicy DeadlinePolicy
+
+	// PanicHandler is an optional hook called whenever a task panics,
+	// for example to report the stack trace to an error tracker.
+	PanicHandler PanicHandler
+
+	// Backoff specifies how long failed tasks wait before they are retried.
+	// When nil, failed tasks are retried immediately.
+	// It is ignored when RetryPolicy is set.
+	Backoff BackoffStrategy
+
+	// RetryPolicy decides whether and when failed tasks are retried.
+	// It defaults to a DefaultRetryPolicy built from MaxRetries and Backoff.
+	RetryPolicy RetryPolicy
+
+	// DeadLetters receives the tasks the pool gave up on.
+	// It defaults to a MemoryDeadLetterSink.
+	DeadLetters DeadLetterSink
+
+	// Logger receives the log messages of the pool. It defaults to a
+	// SlogLogger writing text records to stdout. Use NopLogger to silence
+	// the pool.
+	Logger Logger
+
+	// LogLevel specifies the minimum level of the messages passed to
+	// Logger. It defaults to LevelInfo.
+	LogLevel LogLevel
+
+	// Metrics receives the metrics of the pool, such as a
+	// PrometheusExporter. By default, metrics are only kept in the
+	// counters of the WorkerPool.
+	Metrics MetricsSink
+
+	// Tracer traces the tasks of the pool, with a span per task and a child
+	// span per attempt. Use NewTracer to record spans, or adapt your own
+	// tracing library. Tracing is disabled by default.
+	Tracer Tracer
+
+	// ListenerTimeout specifies how long the pool waits for a listener
+	// subscribed with DeliverSync to handle an event, and how long Stop
+	// waits for listeners to handle pending events. It defaults to one
+	// second.
+	ListenerTimeout time.Duration
+
+	// EventBufferSize specifies the number of events buffered for each
+	// listener. It defaults to 1024.
+	EventBufferSize uint
+
+	// Middleware wraps every task attempt, the first middleware being the
+	// outermost. Attempts run through the chain within the task timeout.
+	Middleware []Middleware
 }
 
 // DefaultWorkerPoolConfig will give a default configuration of WorkerPool
@@ -54,124 +210,389 @@ func DefaultWorkerPoolConfig() *WorkerPoolConfig {
 	}
 }
 
-var logger = newCustomLogger()
-
 // New initializes and returns a new WorkerPool instance with the given configuration.
 // It sets up the task queue and worker count based on the config.
 func New(cfg *WorkerPoolConfig) *WorkerPool {
 	var wg, taskWg sync.WaitGroup
 
-	taskQ := NewTaskQueue(cfg.QueueSize)
+	taskQ := newQueueBackend(cfg)
+	retryPolicy := cfg.RetryPolicy
+	if retryPolicy == nil {
+		retryPolicy = DefaultRetryPolicy{
+			MaxRetries: cfg.MaxRetries,
+			Backoff:    cfg.Backoff,
+		}
+	}
+	deadLetters := cfg.DeadLetters
+	if deadLetters == nil {
+		deadLetters = NewMemoryDeadLetterSink()
+	}
+	log := cfg.Logger
+	if log == nil {
+		log = defaultLogger()
+	}
+	metrics := cfg.Metrics
+	if metrics == nil {
+		metrics = nopMetrics{}
+	}
+	listenerTimeout := cfg.ListenerTimeout
+	if listenerTimeout <= 0 {
+		listenerTimeout = defaultListenerTimeout
+	}
+	eventBufferSize := int(cfg.EventBufferSize)
+	if eventBufferSize == 0 {
+		eventBufferSize = defaultEventBufferSize
+	}
+	ctx, cancel := context.WithCancel(context.Background())
 
-	return &WorkerPool{
-		queue:        taskQ,
-		numOfWorkers: cfg.NumOfWorkers,
-		wg:           &wg,
-		taskWg:       &taskWg,
-		maxRetries:   cfg.MaxRetries,
+	wp := &WorkerPool{
+		queue:           taskQ,
+		numOfWorkers:    cfg.NumOfWorkers,
+		wg:              &wg,
+		taskWg:          &taskWg,
+		timeout:         cfg.TaskTimeout,
+		ctx:             ctx,
+		cancel:          cancel,
+		deadlinePolicy:  cfg.DeadlinePolicy,
+		panicHandler:    cfg.PanicHandler,
+		retryPolicy:     retryPolicy,
+		deadLetters:     deadLetters,
+		cronJobs:        make(map[*CronJob]struct{}),
+		feederWake:      make(chan struct{}, 1),
+		overflow:        cfg.Overflow,
+		spillSize:       int(cfg.SpillSize),
+		logger:          levelLogger{logger: log, min: cfg.LogLevel},
+		metrics:         metrics,
+		tracer:          cfg.Tracer,
+		taskSpans:       make(map[SpanID]Span),
+		listenerTimeout: listenerTimeout,
+		eventBufferSize: eventBufferSize,
+		handler:         chainMiddleware(processTask, cfg.Middleware),
+	}
+	wp.scheduler = newScheduler(wp)
+	if cfg.Autoscale != nil {
+		wp.autoscaler = newAutoscaler(wp, *cfg.Autoscale, cfg.NumOfWorkers)
+		wp.numOfWorkers = wp.autoscaler.clamp(cfg.NumOfWorkers)
 	}
+
+	// Tasks already held by a persistent queue are processed like tasks
+	// enqueued on the pool.
+	wp.taskWg.Add(taskQ.Len())
+	return wp
 }
 
 // EnqueueTask adds a task to the queue for processing and increments the task wait group counter.
+// When the queue is full, the pool's overflow policy applies; see EnqueueContext.
 func (wp *WorkerPool) EnqueueTask(task Task) {
-	wp.queue.Enqueue(task)
-	wp.taskWg.Add(1)
+	if err := wp.enqueueTaskContext(context.Background(), task); err != nil {
+		wp.logger.Log(LevelError, "Failed to enqueue task",
+			Field{Key: "task", Value: taskID(task)},
+			Field{Key: "error", Value: err},
+		)
+	}
 }
 
 // Start begins the task processing by creating worker goroutines.
 // It also records the start time for tracking the task completion duration.
 func (wp *WorkerPool) Start() {
-	logger.Info("Started WorkerPool")
+	wp.StartContext(context.Background())
+}
+
+// StartContext is like Start, but ties the lifetime of the pool to ctx.
+// Once ctx is cancelled, workers stop pulling tasks from the queue and the
+// cancellation is propagated into running tasks that implement ContextTask.
+func (wp *WorkerPool) StartContext(ctx context.Context) {
+	wp.ctx, wp.cancel = context.WithCancel(ctx)
+
+	wp.logger.Log(LevelInfo, "Started WorkerPool", Field{Key: "workers", Value: wp.NumWorkers()})
 	wp.startTime = time.Now()
-	for i := 1; i <= int(wp.numOfWorkers); i++ {
+	wp.wg.Add(2)
+	go wp.scheduler.run(wp.ctx)
+	go wp.feed(wp.ctx)
+	wp.startCron(wp.ctx)
+	if wp.autoscaler != nil {
 		wp.wg.Add(1)
-		go wp.worker(i)
+		go wp.autoscaler.run(wp.ctx)
+	}
+
+	wp.emit(Event{Kind: EventPoolStart})
+
+	wp.workersMu.Lock()
+	defer wp.workersMu.Unlock()
+
+	wp.started = true
+	for i := uint(0); i < wp.numOfWorkers; i++ {
+		wp.spawnWorkerLocked()
 	}
 }
 
 // Stop gracefully stops the WorkerPool by waiting for all tasks to complete.
+// Cron jobs stop enqueueing new runs as soon as Stop is called.
+// If the pool's context is cancelled first, Stop waits only for the running
+// tasks to return and discards the tasks still left in the queue.
 // It closes the task queue and calculates the total time taken for processing.
 func (wp *WorkerPool) Stop() {
-	wp.taskWg.Wait()
-	close(wp.queue.Tasks)
+	wp.stopCron()
+
+	idle := make(chan struct{})
+	go func() {
+		wp.taskWg.Wait()
+		close(idle)
+	}()
+
+	select {
+	case <-idle:
+	case <-wp.ctx.Done():
+	}
+
+	wp.workersMu.Lock()
+	wp.stopped = true
+	wp.workersMu.Unlock()
+
+	wp.cancel()
 	wp.wg.Wait()
+	wp.scheduler.cancelAll(wp.ctx.Err())
+
+	if err := wp.queue.Close(); err != nil {
+		wp.logger.Log(LevelError, "Failed to close queue", Field{Key: "error", Value: err})
+	}
+	for {
+		task, err := wp.queue.Pop(context.Background())
+		if err != nil {
+			break
+		}
+		wp.nack(task, wp.ctx.Err())
+		wp.cancelTask(task, wp.ctx.Err())
+		wp.taskWg.Done()
+	}
+	wp.discardRetries(wp.ctx.Err())
+	wp.discardSpill(wp.ctx.Err())
+	<-idle
+
 	wp.CompletedIn = time.Since(wp.startTime)
+	wp.emit(Event{Kind: EventPoolStop, Duration: wp.CompletedIn})
+	wp.closeListeners()
 }
 
 // Summary logs the statistics of the worker pool execution, including
 // the number of processed tasks, successes, failures, and total time taken.
 func (wp *WorkerPool) Summary() {
-	fmt.Println("-------------------------------------------------------------------------------")
-	msg := fmt.Sprintf(
-		"\n- Processed %d Tasks \n- Worker Count %d\n- %d Success \n- %d Failed \n- Completed in %v",
-		wp.ProcessedTasks,
-		wp.numOfWorkers,
-		wp.TaskSuccess,
-		wp.TaskFailure,
-		wp.CompletedIn,
+	wp.logger.Log(LevelInfo, "Summary",
+		Field{Key: "processed", Value: atomic.LoadUint32(&wp.ProcessedTasks)},
+		Field{Key: "workers", Value: wp.NumWorkers()},
+		Field{Key: "worker_ids", Value: formatWorkerIDs(wp.WorkerIDs())},
+		Field{Key: "scheduled", Value: atomic.LoadUint32(&wp.TaskScheduled)},
+		Field{Key: "success", Value: atomic.LoadUint32(&wp.TaskSuccess)},
+		Field{Key: "failed", Value: atomic.LoadUint32(&wp.TaskFailure)},
+		Field{Key: "timed_out_attempts", Value: atomic.LoadUint32(&wp.TaskTimeouts)},
+		Field{Key: "panicked_attempts", Value: atomic.LoadUint32(&wp.TaskPanics)},
+		Field{Key: "cancelled", Value: atomic.LoadUint32(&wp.TaskCancelled)},
+		Field{Key: "rejected", Value: atomic.LoadUint32(&wp.TaskRejected)},
+		Field{Key: "dropped", Value: atomic.LoadUint32(&wp.TaskDropped)},
+		Field{Key: "spilled", Value: atomic.LoadUint32(&wp.TaskSpilled)},
+		Field{Key: "completed_in", Value: wp.CompletedIn},
 	)
-	logger.CustomTag("[SUMMARY] ", msg)
 }
 
 // worker is the main loop for each worker that pulls tasks from the queue
-// and processes them.
-func (wp *WorkerPool) worker(id int) {
+// and processes them until the queue is closed, the pool is cancelled or
+// the worker is removed through ctx. Tasks are always processed with the
+// pool's context, so removing a worker lets its current task finish.
+func (wp *WorkerPool) worker(ctx context.Context, id int) {
 	defer wp.wg.Done()
 
-	for task := range wp.queue.Tasks {
+	for {
+		task, err := wp.queue.Pop(ctx)
+		if err != nil {
+			return
+		}
+		wp.popped.Add(1)
+		wp.drainRetries()
+		wp.drainSpill()
+		wp.reportQueueDepth()
+
+		wp.metrics.BusyWorkers(int(atomic.AddInt32(&wp.busyWorkers, 1)))
 		wp.handleTask(id, task)
+		wp.metrics.BusyWorkers(int(atomic.AddInt32(&wp.busyWorkers, -1)))
 	}
 }
 
-// handleTask processes a single task, handling retries if the task implements
-// the retryableTask interface. It logs success, retries, or final failure after
-// exhausting retry attempts.
+// handleTask processes a single task, handling retries as decided by the
+// retry policy if the task implements the RetryableTask interface. Errors
+// marked with Permanent are never retried. Tasks the pool gives up on are
+// handed to the dead-letter sink. It logs success, retries, or final failure after
+// exhausting retry attempts. Tasks interrupted by cancellation of the pool are
+// neither retried nor counted as failures.
 func (wp *WorkerPool) handleTask(id int, task Task) {
 	defer wp.taskWg.Done()
 
-	for {
-		err := task.Process()
-		if err == nil {
-			atomic.AddUint32(&wp.TaskSuccess, 1)
-			atomic.AddUint32(&wp.ProcessedTasks, 1)
+	if wp.ctx.Err() != nil {
+		wp.nack(task, wp.ctx.Err())
+		wp.cancelTask(task, wp.ctx.Err())
+		return
+	}
+
+	attempt := uint(1)
+	if rt, ok := task.(RetryableTask); ok {
+		attempt = rt.Retries() + 1
+	}
+
+	ctx, endAttempt := wp.startAttemptSpan(wp.ctx, id, task, attempt)
+	wp.metrics.TaskStarted()
+	wp.emit(Event{Kind: EventStart, Task: task, Worker: id, Attempt: attempt})
+	startedAt := time.Now()
+	abandoned, err := wp.runTask(ctx, task)
+	duration := time.Since(startedAt)
+
+	if err == nil {
+		atomic.AddUint32(&wp.TaskSuccess, 1)
+		atomic.AddUint32(&wp.ProcessedTasks, 1)
+		wp.metrics.TaskSucceeded(duration)
+		endAttempt(nil)
+		wp.emit(Event{Kind: EventSuccess, Task: task, Worker: id, Attempt: attempt, Duration: duration})
+		wp.ack(task)
+		wp.endTaskSpan(task, nil)
+		completeTask(task, nil)
+		return
+	}
+
+	if wp.ctx.Err() != nil || errors.Is(err, ErrScheduleCancelled) {
+		endAttempt(err)
+		wp.nack(task, err)
+		wp.cancelTask(task, err)
+		wp.logger.Log(LevelWarn, "Task cancelled", taskFields(id, task, attempt, err, duration)...)
+		return
+	}
+
+	if errors.Is(err, ErrTaskTimeout) {
+		atomic.AddUint32(&wp.TaskTimeouts, 1)
+	}
+
+	var perr *PanicError
+	if errors.As(err, &perr) {
+		atomic.AddUint32(&wp.TaskPanics, 1)
+		wp.handlePanic(task, perr)
+	}
+
+	if rt, ok := task.(RetryableTask); ok {
+		retries := rt.Retries()
+		if delay, retry := wp.retryDecision(task, err, retries); retry {
+			endAttempt(err, Field{Key: "retry_in", Value: delay})
+			wp.emit(Event{Kind: EventRetry, Task: task, Worker: id, Attempt: attempt, Err: err, Duration: duration, RetryIn: delay})
+			wp.retryTask(rt, retries+1, err, delay, abandoned)
+			wp.metrics.TaskRetried(duration)
+
+			fields := taskFields(id, task, attempt, err, duration)
+			fields = append(fields, Field{Key: "retry_in", Value: delay})
+			wp.logger.Log(LevelWarn, "Task failed, retrying", fields...)
 			return
 		}
 
-		if tm, ok := task.(retryableTask); ok {
-			if tm.retry(wp.maxRetries) {
-				wp.queue.Enqueue(task)
-				wp.taskWg.Add(1)
-
-				msg := fmt.Sprintf(
-					"Worker %d failed: %s (attempt %d)",
-					id,
-					err.Error(),
-					tm.getRetry(),
-				)
-				logger.Warn(msg)
-				return
-			}
-
-			atomic.AddUint32(&wp.TaskFailure, 1)
-			atomic.AddUint32(&wp.ProcessedTasks, 1)
-
-			msg := fmt.Sprintf(
-				"Worker %d gave up after %d retries: %s",
-				id,
-				wp.maxRetries,
-				err.Error(),
-			)
-			logger.Error(msg)
+		atomic.AddUint32(&wp.TaskFailure, 1)
+		atomic.AddUint32(&wp.ProcessedTasks, 1)
+
+		wp.logger.Log(LevelError, "Task failed, giving up", taskFields(id, task, attempt, err, duration)...)
+		wp.metrics.TaskFailed(duration)
+		endAttempt(err)
+		wp.emit(Event{Kind: EventFailure, Task: task, Worker: id, Attempt: attempt, Err: err, Duration: duration})
+		wp.ack(task)
+		wp.deadLetter(task, err, retries+1, startedAt)
+		wp.endTaskSpan(task, err)
+		completeTask(task, err)
+		return
+	}
+
+	atomic.AddUint32(&wp.ProcessedTasks, 1)
+	atomic.AddUint32(&wp.TaskFailure, 1)
+	wp.logger.Log(LevelError, "Task failed", taskFields(id, task, attempt, err, duration)...)
+	wp.metrics.TaskFailed(duration)
+	endAttempt(err)
+	wp.emit(Event{Kind: EventFailure, Task: task, Worker: id, Attempt: attempt, Err: err, Duration: duration})
+	wp.ack(task)
+	wp.deadLetter(task, err, 1, startedAt)
+	wp.endTaskSpan(task, err)
+	completeTask(task, err)
+}
+
+// retryTask records retries as the retry count of task, hands it back to
+// the queue with cause and puts it back on the queue after delay. A delayed
+// task waits in the scheduler, so the worker is free to process other tasks
+// meanwhile. The worker never waits for room in the queue either; see
+// retryLane.
+//
+// If the failed attempt was abandoned, all of this happens once the attempt
+// returns, so the task is never touched while it is still running. The
+// worker does not wait for it.
+func (wp *WorkerPool) retryTask(task RetryableTask, retries uint, cause error, delay time.Duration, abandoned <-chan struct{}) {
+	wp.taskWg.Add(1)
+
+	if abandoned == nil {
+		wp.cancelRefused(wp.scheduleRetry(task, retries, cause, delay))
+		return
+	}
+	go func() {
+		<-abandoned
+
+		// Stop sets stopped under workersMu before it cancels the queue
+		// and the scheduler, so the task cannot slip past them.
+		wp.workersMu.Lock()
+		stopped := wp.stopped
+		var refused []refusal
+		if !stopped {
+			refused = wp.scheduleRetry(task, retries, cause, delay)
+		}
+		wp.workersMu.Unlock()
+
+		if stopped {
+			// Stop cancels the pool right after setting stopped.
+			<-wp.ctx.Done()
+			wp.nack(task, cause)
+			wp.cancelTask(task, wp.ctx.Err())
+			wp.taskWg.Done()
 			return
 		}
+		wp.cancelRefused(refused)
+	}()
+}
 
-		atomic.AddUint32(&wp.ProcessedTasks, 1)
-		atomic.AddUint32(&wp.TaskFailure, 1)
-		msg := fmt.Sprintf(
-			"Worker %d Failed to parse task: %v",
-			id,
-			task,
+// scheduleRetry is the part of retryTask that runs once the failed attempt
+// of task has returned. It returns the tasks the queue refused, for the
+// caller to abandon.
+func (wp *WorkerPool) scheduleRetry(task RetryableTask, retries uint, cause error, delay time.Duration) []refusal {
+	task.SetRetries(retries)
+	wp.nack(task, cause)
+	if delay <= 0 {
+		return wp.pushRetry(task)
+	}
+	wp.scheduler.schedule(task, time.Now().Add(delay))
+	return nil
+}
+
+// cancelTask accounts for a task abandoned before it could complete, because
+// the pool was cancelled or its schedule was cancelled.
+func (wp *WorkerPool) cancelTask(task Task, err error) {
+	atomic.AddUint32(&wp.TaskCancelled, 1)
+	wp.emitTask(EventDrop, task, err)
+	wp.endTaskSpan(task, err)
+	completeTask(task, err)
+}
+
+// ack reports to the queue that task reached its final outcome.
+func (wp *WorkerPool) ack(task Task) {
+	if err := wp.queue.Ack(task); err != nil {
+		wp.logger.Log(LevelError, "Failed to ack task",
+			Field{Key: "task", Value: taskID(task)},
+			Field{Key: "error", Value: err},
+		)
+	}
+}
+
+// nack reports to the queue that task was handed back without a final outcome.
+func (wp *WorkerPool) nack(task Task, cause error) {
+	if err := wp.queue.Nack(task, cause); err != nil {
+		wp.logger.Log(LevelError, "Failed to nack task",
+			Field{Key: "task", Value: taskID(task)},
+			Field{Key: "error", Value: err},
 		)
-		logger.Error(msg)
 	}
 }